@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/siad/types"
+)
+
+// archive reasons
+const (
+	archiveReasonExpired = "expired"
+	archiveReasonRenewed = "renewed"
+)
+
+// An archivedContract is the final state of a contract that has been removed
+// from renterd.
+type archivedContract struct {
+	ID            types.FileContractID `json:"id"`
+	HostKey       string               `json:"hostKey"`
+	Reason        string               `json:"reason"`
+	ArchiveHeight uint64               `json:"archiveHeight"`
+	ArchivedAt    time.Time            `json:"archivedAt"`
+	// Contract is the last revision renterd had. For renewed contracts it is
+	// the revision before the renewal cleared the contract.
+	Contract rhp.Contract `json:"contract"`
+	// Record is renterc's record of the contract, it is nil if the contract
+	// was not formed by renterc.
+	Record   *contractRecord   `json:"record,omitempty"`
+	Spending *contractSpending `json:"spending,omitempty"`
+}
+
+var (
+	archivedCmd = &cobra.Command{
+		Use:   "archived",
+		Short: "list archived contracts or the details of a single archived contract",
+		Long: `renterc contracts archived [contract id]
+
+Lists the contracts that were removed from renterd after they expired or were renewed. The last revision renterd had, spending and reason are kept in the data directory. For renewed contracts this is the revision from before the renewal.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("at most one contract id is allowed")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				var id types.FileContractID
+				if err := id.LoadString(args[0]); err != nil {
+					log.Fatalln(err)
+				}
+				ac, err := loadArchivedContract(dataDir, id)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				js, _ := json.MarshalIndent(ac, "", "  ")
+				log.Println(string(js))
+				return
+			}
+
+			archived, err := loadArchivedContracts(dataDir)
+			if err != nil {
+				log.Fatalln("failed to load archived contracts:", err)
+			}
+
+			tbl := table.New("ID", "Host", "Reason", "Archive Height", "Initial Funds", "Remaining", "Data Stored")
+			for _, ac := range archived {
+				initial := "-"
+				if ac.Spending != nil {
+					initial = ac.Spending.InitialFunds.HumanString()
+				}
+				tbl.AddRow(ac.ID, ac.HostKey, ac.Reason, ac.ArchiveHeight, initial, ac.Contract.RenterFunds().HumanString(), formatByteStr(ac.Contract.Revision.NewFileSize))
+			}
+			tbl.Print()
+		},
+	}
+)
+
+// contractArchiveDir returns the directory archived contracts are stored in.
+func contractArchiveDir(dataDir string) string {
+	return filepath.Join(dataDir, "archive")
+}
+
+// archiveContract stores the contract's revision and spending in the data
+// directory and then removes it from renterd. The contract is not
+// removed if it could not be archived.
+func archiveContract(dataDir string, c rhp.Contract, height uint64, reason string) error {
+	ac := archivedContract{
+		ID:            c.ID(),
+		HostKey:       c.HostKey().String(),
+		Reason:        reason,
+		ArchiveHeight: height,
+		ArchivedAt:    time.Now(),
+		Contract:      c,
+	}
+
+	records, err := loadContractRecords(dataDir)
+	if err != nil {
+		return err
+	} else if record, ok := records[c.ID().String()]; ok {
+		spending := estimateSpending(c, record)
+		ac.Record = &record
+		ac.Spending = &spending
+	}
+
+	buf, err := json.MarshalIndent(ac, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archived contract: %w", err)
+	} else if err := os.MkdirAll(contractArchiveDir(dataDir), 0700); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	} else if err := os.WriteFile(filepath.Join(contractArchiveDir(dataDir), c.ID().String()+".json"), buf, 0600); err != nil {
+		return fmt.Errorf("failed to write archived contract: %w", err)
+	} else if err := renterdClient.DeleteContract(c.ID()); err != nil {
+		return fmt.Errorf("failed to remove contract: %w", err)
+	} else if err := removeContractRecord(dataDir, c.ID()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadArchivedContract loads a single archived contract from the data
+// directory.
+func loadArchivedContract(dataDir string, id types.FileContractID) (ac archivedContract, err error) {
+	buf, err := os.ReadFile(filepath.Join(contractArchiveDir(dataDir), id.String()+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return archivedContract{}, fmt.Errorf("contract %v is not archived", id)
+	} else if err != nil {
+		return archivedContract{}, fmt.Errorf("failed to read archived contract: %w", err)
+	} else if err := json.Unmarshal(buf, &ac); err != nil {
+		return archivedContract{}, fmt.Errorf("failed to decode archived contract: %w", err)
+	}
+	return ac, nil
+}
+
+// loadArchivedContracts loads every archived contract from the data
+// directory, oldest first.
+func loadArchivedContracts(dataDir string) ([]archivedContract, error) {
+	entries, err := os.ReadDir(contractArchiveDir(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	var archived []archivedContract
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(contractArchiveDir(dataDir), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archived contract %v: %w", entry.Name(), err)
+		}
+		var ac archivedContract
+		if err := json.Unmarshal(buf, &ac); err != nil {
+			return nil, fmt.Errorf("failed to decode archived contract %v: %w", entry.Name(), err)
+		}
+		archived = append(archived, ac)
+	}
+	sort.Slice(archived, func(i, j int) bool { return archived[i].ArchiveHeight < archived[j].ArchiveHeight })
+	return archived, nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/siad/types"
+)
+
+// maxHostFailures is the number of consecutive failed scans before a host is
+// considered unresponsive and its contract is replaced.
+const maxHostFailures = 3
+
+// autopilot command args
+var (
+	autopilotInterval    time.Duration
+	autopilotHosts       int
+	autopilotAllowance   string
+	autopilotRenewWindow string
+	autopilotDurationStr string
+)
+
+type (
+	// autopilotState is persisted in the data directory between iterations
+	// and restarts of the autopilot.
+	autopilotState struct {
+		// PeriodStart is the height the current allowance period started.
+		PeriodStart uint64 `json:"periodStart"`
+		// Spent is the amount spent on formations and renewals in the
+		// current period.
+		Spent types.Currency `json:"spent"`
+		// Failures is the number of consecutive failed scans for each host.
+		Failures map[string]int `json:"failures"`
+	}
+
+	// autopilotConfig is the configuration for an autopilot iteration.
+	autopilotConfig struct {
+		Hosts       int
+		Allowance   types.Currency
+		Usage       uint64
+		Duration    uint64
+		RenewWindow uint64
+	}
+)
+
+var (
+	autopilotCmd = &cobra.Command{
+		Use:   "autopilot",
+		Short: "automatically maintain a set of contracts",
+		Long: `renterc autopilot [flags]
+
+Runs until interrupted, maintaining a healthy set of contracts. Every --interval the autopilot:
+
+  - scans the host of every contract, marking hosts that fail 3 scans in a row as unresponsive
+  - renews contracts whose proof window starts within --renew-window
+  - forms contracts with new hosts from --host-source until there are --hosts contracts with responsive hosts
+
+Formations and renewals are funded for --usage bytes and last --duration blocks. The total spent in each period of --duration blocks is limited to --allowance. Every decision is logged.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			allowance, err := parseCurrency(autopilotAllowance)
+			if err != nil {
+				log.Fatalln("failed to parse allowance:", err)
+			}
+			usage, err := parseByteStr(contractUsageStr)
+			if err != nil {
+				log.Fatalln("failed to parse contract usage:", err)
+			}
+			duration, err := parseBlockDurStr(autopilotDurationStr)
+			if err != nil {
+				log.Fatalln("failed to parse contract duration:", err)
+			}
+			renewWindow, err := parseBlockDurStr(autopilotRenewWindow)
+			if err != nil {
+				log.Fatalln("failed to parse renew window:", err)
+			}
+
+			cfg := autopilotConfig{
+				Hosts:       autopilotHosts,
+				Allowance:   allowance,
+				Usage:       usage,
+				Duration:    duration,
+				RenewWindow: renewWindow,
+			}
+
+			state, err := loadAutopilotState(dataDir)
+			if err != nil {
+				log.Fatalln("failed to load autopilot state:", err)
+			}
+
+			log.Printf("Starting autopilot: %v hosts, %v allowance, checking every %v", cfg.Hosts, cfg.Allowance.HumanString(), autopilotInterval)
+			for {
+				if err := runAutopilot(renterPriv, cfg, state); err != nil {
+					log.Println("autopilot iteration failed:", err)
+				}
+				if err := saveAutopilotState(dataDir, state); err != nil {
+					log.Println("failed to save autopilot state:", err)
+				}
+				time.Sleep(autopilotInterval)
+			}
+		},
+	}
+)
+
+// autopilotStatePath returns the path of the autopilot state file.
+func autopilotStatePath(dataDir string) string {
+	return filepath.Join(dataDir, "autopilot.json")
+}
+
+// loadAutopilotState loads the autopilot state from the data directory.
+func loadAutopilotState(dataDir string) (*autopilotState, error) {
+	state := &autopilotState{
+		Failures: make(map[string]int),
+	}
+	buf, err := os.ReadFile(autopilotStatePath(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read autopilot state: %w", err)
+	} else if err := json.Unmarshal(buf, state); err != nil {
+		return nil, fmt.Errorf("failed to decode autopilot state: %w", err)
+	} else if state.Failures == nil {
+		state.Failures = make(map[string]int)
+	}
+	return state, nil
+}
+
+// saveAutopilotState writes the autopilot state to the data directory.
+func saveAutopilotState(dataDir string, state *autopilotState) error {
+	buf, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode autopilot state: %w", err)
+	}
+	return os.WriteFile(autopilotStatePath(dataDir), buf, 0600)
+}
+
+// remaining returns the unspent allowance for the current period.
+func (s *autopilotState) remaining(allowance types.Currency) types.Currency {
+	if s.Spent.Cmp(allowance) >= 0 {
+		return types.ZeroCurrency
+	}
+	return allowance.Sub(s.Spent)
+}
+
+// runAutopilot runs a single iteration of the autopilot.
+func runAutopilot(renterPriv api.PrivateKey, cfg autopilotConfig, state *autopilotState) error {
+	tip, err := renterdClient.ConsensusTip()
+	if err != nil {
+		return fmt.Errorf("failed to get consensus tip: %w", err)
+	}
+
+	// start a new allowance period once the previous one has ended
+	if state.PeriodStart == 0 || tip.Height >= state.PeriodStart+cfg.Duration {
+		log.Printf("Starting new allowance period at height %v", tip.Height)
+		state.PeriodStart = tip.Height
+		state.Spent = types.ZeroCurrency
+	}
+
+	contracts, err := renterdClient.Contracts()
+	if err != nil {
+		return fmt.Errorf("failed to get contracts: %w", err)
+	}
+
+	// scan the host of every live contract
+	existing := make(map[api.PublicKey]bool)
+	var active int
+	for _, c := range contracts {
+		hostKey := c.HostKey()
+		existing[hostKey] = true
+		if tip.Height > c.EndHeight() {
+			continue
+		}
+
+		netAddress, err := hostAddress(hostKey)
+		if err == nil {
+			_, err = renterdClient.RHPScan(hostKey, netAddress)
+		}
+		if err != nil {
+			state.Failures[hostKey.String()]++
+			log.Printf("Host %v failed scan (%v/%v): %v", hostKey, state.Failures[hostKey.String()], maxHostFailures, err)
+			if state.Failures[hostKey.String()] >= maxHostFailures {
+				log.Printf("Host %v is unresponsive, contract %v will be replaced", hostKey, c.ID())
+			} else {
+				// give the host a chance to come back before replacing it
+				active++
+			}
+			continue
+		}
+		delete(state.Failures, hostKey.String())
+
+		// renew contracts approaching their proof window
+		windowStart := uint64(c.Revision.NewWindowStart)
+		if tip.Height >= windowStart {
+			log.Printf("Contract %v is in its proof window and can no longer be renewed", c.ID())
+			continue
+		} else if windowStart-tip.Height > cfg.RenewWindow {
+			active++
+			continue
+		}
+
+		remaining := state.remaining(cfg.Allowance)
+		if remaining.IsZero() {
+			log.Printf("Skipping renewal of contract %v, allowance exhausted", c.ID())
+			continue
+		}
+		log.Printf("Renewing contract %v, proof window starts in %v blocks", c.ID(), windowStart-tip.Height)
+		renewed, cost, err := renewContract(renterPriv, c.ID(), tip.Height+cfg.Duration, usageFunds(uniformUsage(cfg.Usage)), remaining)
+		if err != nil {
+			log.Printf("Failed to renew contract %v: %v", c.ID(), err)
+			continue
+		}
+		state.Spent = state.Spent.Add(cost)
+		active++
+		log.Printf("Renewed contract %v as %v for %v, %v allowance remaining", c.ID(), renewed.ID(), cost.HumanString(), state.remaining(cfg.Allowance).HumanString())
+	}
+
+	if active >= cfg.Hosts {
+		log.Printf("%v of %v target hosts have active contracts", active, cfg.Hosts)
+		return nil
+	}
+
+	// form contracts with new hosts to replace the missing ones
+	needed := cfg.Hosts - active
+	log.Printf("%v of %v target hosts have active contracts, forming %v new contracts", active, cfg.Hosts, needed)
+	candidates, err := candidateHosts()
+	if err != nil {
+		return fmt.Errorf("failed to get candidate hosts: %w", err)
+	}
+	for _, candidate := range candidates {
+		if needed == 0 {
+			break
+		}
+
+		hostKey := candidate.PublicKey
+		if existing[hostKey] {
+			continue
+		} else if candidate.Settings != nil && !candidate.Settings.AcceptingContracts {
+			continue
+		}
+		existing[hostKey] = true
+
+		remaining := state.remaining(cfg.Allowance)
+		if remaining.IsZero() {
+			log.Printf("Allowance exhausted, %v contracts still needed", needed)
+			return nil
+		}
+		log.Printf("Forming contract with host %v", hostKey)
+		id, cost, err := formContract(renterPriv, hostKey, candidate.NetAddress, uniformUsage(cfg.Usage), cfg.Duration, remaining)
+		if err != nil {
+			log.Printf("Failed to form contract with host %v: %v", hostKey, err)
+			continue
+		}
+		state.Spent = state.Spent.Add(cost)
+		needed--
+		log.Printf("Formed contract %v with host %v for %v, %v allowance remaining", id, hostKey, cost.HumanString(), state.remaining(cfg.Allowance).HumanString())
+	}
+	if needed > 0 {
+		log.Printf("Not enough candidate hosts, %v contracts still needed", needed)
+	}
+	return nil
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/rodaine/table"
 	"github.com/spf13/cobra"
 	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/rhp/v2"
 	"go.sia.tech/renterd/wallet"
 	"go.sia.tech/siad/types"
 )
@@ -16,6 +18,17 @@ import (
 var (
 	contractDurationStr string
 	contractUsageStr    string
+	contractStorageStr  string
+	contractUploadStr   string
+	contractDownloadStr string
+	maxCostStr          string
+	formParallel        int
+	expiringWithinStr   string
+	refillAmountStr     string
+	refillThresholdStr  string
+	hostAddrFlag        string
+	autoHosts           int
+	formBudgetStr       string
 )
 
 var (
@@ -59,19 +72,224 @@ var (
 		},
 	}
 
+	renewCmd = &cobra.Command{
+		Use:   "renew",
+		Short: "renew contract(s) with their hosts",
+		Long: `renterc contracts renew [flags] <contract id 1> [contract id 2 ...]
+renterc contracts renew --expiring-within 1w
+
+Renews contracts with the same host. The data stored in each contract is carried forward and the contract is funded for --usage bytes of storage, upload and download until --duration blocks from now. The renewed contract replaces the old contract in renterd.
+
+With --expiring-within, every contract whose proof window starts within the duration is renewed.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if expiringWithinStr != "" && len(args) != 0 {
+				return errors.New("contract ids are not allowed when using --expiring-within")
+			} else if expiringWithinStr == "" && len(args) == 0 {
+				return errors.New("at least one contract id or --expiring-within is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			contractUsage, err := parseByteStr(contractUsageStr)
+			if err != nil {
+				log.Fatalln("failed to parse contract usage:", err)
+			}
+			contractDuration, err := parseBlockDurStr(contractDurationStr)
+			if err != nil {
+				log.Fatalln("failed to parse contract duration:", err)
+			}
+
+			tip, err := renterdClient.ConsensusTip()
+			if err != nil {
+				log.Fatalln("failed to get consensus tip:", err)
+			}
+
+			var ids []types.FileContractID
+			if expiringWithinStr != "" {
+				within, err := parseBlockDurStr(expiringWithinStr)
+				if err != nil {
+					log.Fatalln("failed to parse expiring within:", err)
+				}
+
+				contracts, err := renterdClient.Contracts()
+				if err != nil {
+					log.Fatalln("failed to get contracts:", err)
+				}
+				for _, c := range contracts {
+					windowStart := uint64(c.Revision.NewWindowStart)
+					// contracts in their proof window can no longer be renewed
+					if tip.Height < windowStart && windowStart-tip.Height <= within {
+						ids = append(ids, c.ID())
+					}
+				}
+				if len(ids) == 0 {
+					log.Println("No contracts expiring within", expiringWithinStr)
+					return
+				}
+			} else {
+				for _, arg := range args {
+					var id types.FileContractID
+					if err := id.LoadString(arg); err != nil {
+						log.Fatalf("failed to parse contract id %v: %v", arg, err)
+					}
+					ids = append(ids, id)
+				}
+			}
+
+			var failed int
+			for i, id := range ids {
+				log.Printf("Renewing contract %v (%v/%v)", id, i+1, len(ids))
+				contract, cost, err := renewContract(renterPriv, id, tip.Height+contractDuration, usageFunds(uniformUsage(contractUsage)), types.ZeroCurrency)
+				if err != nil {
+					log.Printf("failed to renew contract %v: %v", id, err)
+					failed++
+					continue
+				}
+				log.Printf("Renewed contract %v as %v for %v, expires at height %v", id, contract.ID(), cost.HumanString(), contract.EndHeight())
+			}
+			if failed > 0 {
+				log.Fatalf("failed to renew %v of %v contracts", failed, len(ids))
+			}
+		},
+	}
+
+	refillCmd = &cobra.Command{
+		Use:   "refill",
+		Short: "add funds to contract(s)",
+		Long: `renterc contracts refill --amount <amount> <contract id 1> [contract id 2 ...]
+renterc contracts refill --amount <amount> --threshold <amount>
+
+Adds --amount to the remaining renter funds of contracts by renewing them with the same host to the same end height. The stored data is carried forward and the refilled contract replaces the old contract in renterd. The old contract's remaining funds are returned when its proof window ends.
+
+With --threshold, every contract with less than the threshold in remaining renter funds is refilled.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if refillAmountStr == "" {
+				return errors.New("--amount is required")
+			} else if refillThresholdStr != "" && len(args) != 0 {
+				return errors.New("contract ids are not allowed when using --threshold")
+			} else if refillThresholdStr == "" && len(args) == 0 {
+				return errors.New("at least one contract id or --threshold is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			amount, err := parseCurrency(refillAmountStr)
+			if err != nil {
+				log.Fatalln("failed to parse amount:", err)
+			}
+
+			tip, err := renterdClient.ConsensusTip()
+			if err != nil {
+				log.Fatalln("failed to get consensus tip:", err)
+			}
+
+			var contracts []rhp.Contract
+			if refillThresholdStr != "" {
+				threshold, err := parseCurrency(refillThresholdStr)
+				if err != nil {
+					log.Fatalln("failed to parse threshold:", err)
+				}
+
+				all, err := renterdClient.Contracts()
+				if err != nil {
+					log.Fatalln("failed to get contracts:", err)
+				}
+				for _, c := range all {
+					// contracts in their proof window can no longer be renewed
+					if tip.Height < uint64(c.Revision.NewWindowStart) && c.RenterFunds().Cmp(threshold) < 0 {
+						contracts = append(contracts, c)
+					}
+				}
+				if len(contracts) == 0 {
+					log.Println("No contracts below", threshold.HumanString())
+					return
+				}
+			} else {
+				for _, arg := range args {
+					var id types.FileContractID
+					if err := id.LoadString(arg); err != nil {
+						log.Fatalf("failed to parse contract id %v: %v", arg, err)
+					}
+					c, err := renterdClient.Contract(id)
+					if err != nil {
+						log.Fatalf("failed to get contract %v: %v", id, err)
+					}
+					contracts = append(contracts, c)
+				}
+			}
+
+			var failed int
+			for i, c := range contracts {
+				log.Printf("Refilling contract %v with %v remaining (%v/%v)", c.ID(), c.RenterFunds().HumanString(), i+1, len(contracts))
+				refilled, cost, err := renewContract(renterPriv, c.ID(), c.EndHeight(), refillFunds(c, amount), types.ZeroCurrency)
+				if err != nil {
+					log.Printf("failed to refill contract %v: %v", c.ID(), err)
+					failed++
+					continue
+				}
+				log.Printf("Refilled contract %v as %v for %v, %v remaining", c.ID(), refilled.ID(), cost.HumanString(), refilled.RenterFunds().HumanString())
+			}
+			if failed > 0 {
+				log.Fatalf("failed to refill %v of %v contracts", failed, len(contracts))
+			}
+		},
+	}
+
 	formCmd = &cobra.Command{
 		Use:   "form",
 		Short: "form a contract with host(s)",
-		Long:  "renterc contracts form [flags] <host public key 1> [host public key 2 ...]",
+		Long: `renterc contracts form [flags] <host public key 1>[@<address>] [host public key 2 ...]
+
+renterc contracts form --auto <n> [--budget <amount>]
+
+Forms contracts with the hosts, up to --parallel at a time. Each host's address is looked up from --host-source unless it is given after the public key or with --addr. A quote for each contract is printed and must be confirmed before any funds are spent, unless -y is given. A summary of every formation is printed at the end, and the command fails if any formation failed.
+
+Contracts are funded for --storage bytes of storage, --upload bytes of upload and --download bytes of download lasting --duration blocks. The host's collateral is scaled to the storage. Formation with a host is aborted if its quote, including the miner fee, exceeds --max-cost.
+
+With --auto, the best n hosts without an active contract are chosen automatically from --host-source. Candidates are ranked by their price for the usage and duration, uptime and age, then scanned to confirm they are online and re-ranked by their current prices. If a formation fails, the next best host is tried. --budget limits the total spent on all formations, including miner fees.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if autoHosts < 0 {
+				return errors.New("--auto must be positive")
+			} else if autoHosts > 0 && len(args) != 0 {
+				return errors.New("host keys are not allowed when using --auto")
+			} else if autoHosts == 0 && formBudgetStr != "" {
+				return errors.New("--budget can only be used with --auto")
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, hostKeys []string) {
-			contractUsage, err := parseByteStr(contractUsageStr)
+			contractUsage, err := parseUsageFlags()
 			if err != nil {
-				log.Fatalln("failed to parse contract usage:", err)
+				log.Fatalln(err)
 			}
 			contractDuration, err := parseBlockDurStr(contractDurationStr)
 			if err != nil {
 				log.Fatalln("failed to parse contract duration:", err)
 			}
+			maxCost := types.ZeroCurrency
+			if maxCostStr != "" {
+				maxCost, err = parseCurrency(maxCostStr)
+				if err != nil {
+					log.Fatalln("failed to parse max cost:", err)
+				}
+			}
+
+			if autoHosts > 0 {
+				budget := types.ZeroCurrency
+				if formBudgetStr != "" {
+					budget, err = parseCurrency(formBudgetStr)
+					if err != nil {
+						log.Fatalln("failed to parse budget:", err)
+					}
+				}
+				formed, err := autoFormContracts(renterPriv, autoHosts, contractUsage, contractDuration, budget, maxCost, !skipConfirm)
+				if err != nil {
+					log.Fatalln("failed to form contracts:", err)
+				} else if formed < autoHosts {
+					log.Fatalf("formed %v of %v contracts", formed, autoHosts)
+				}
+				return
+			}
 
 			switch len(hostKeys) {
 			case 0:
@@ -82,93 +300,356 @@ var (
 				log.Printf("Forming contract with %v hosts", len(hostKeys))
 			}
 
-			for i, host := range hostKeys {
-				if len(hostKeys) > 1 {
-					log.Printf("Forming contract with host %v (%v/%v)", host, i+1, len(hostKeys))
-				}
-				var hostKey api.PublicKey
-				err := hostKey.UnmarshalText([]byte(hostKeys[0]))
+			if hostAddrFlag != "" && len(hostKeys) != 1 {
+				log.Fatalln("--addr can only be used with a single host, use <public key>@<address> instead")
+			}
+
+			results := make([]formResult, len(hostKeys))
+			for i, arg := range hostKeys {
+				hostKey, hostAddr, err := parseHostArg(arg)
 				if err != nil {
-					log.Fatalln("failed to parse host key:", err)
+					log.Fatalf("failed to parse host %v: %v", arg, err)
+				} else if hostAddrFlag != "" {
+					hostAddr = hostAddrFlag
 				}
-				contractID, err := formContract(renterPriv, hostKey, contractUsage, contractDuration)
+				results[i] = formResult{HostKey: hostKey, NetAddress: hostAddr}
+			}
+
+			// quote every contract before spending any funds
+			forEachConcurrent(len(results), formParallel, func(i int) {
+				r := &results[i]
+				q, err := quoteContract(renterPriv, r.HostKey, r.NetAddress, contractUsage, contractDuration)
 				if err != nil {
-					log.Println("failed to form contract:", err)
+					r.Err = fmt.Errorf("failed to quote contract: %w", err)
+					return
+				} else if !maxCost.IsZero() && q.Total.Cmp(maxCost) > 0 {
+					r.Err = fmt.Errorf("contract cost %v exceeds max cost %v", q.Total.HumanString(), maxCost.HumanString())
+					return
+				}
+				r.quote = &q
+			})
+
+			var quotes []contractQuote
+			for _, r := range results {
+				if r.quote != nil {
+					quotes = append(quotes, *r.quote)
+				}
+			}
+			if len(quotes) > 0 {
+				printQuotes(quotes)
+				if !skipConfirm && !promptConfirm(fmt.Sprintf("Form %v contracts for %v?", len(quotes), sumQuotes(quotes).HumanString())) {
+					log.Fatalln("aborted")
+				}
+
+				forEachConcurrent(len(results), formParallel, func(i int) {
+					r := &results[i]
+					if r.quote == nil {
+						return
+					}
+					r.ID, r.Cost, r.Err = formQuotedContract(renterPriv, *r.quote, maxCost)
+					if r.Err == nil {
+						log.Printf("Formed contract %v with host %v", r.ID, r.HostKey)
+					}
+				})
+			}
+
+			var failed int
+			spent := types.ZeroCurrency
+			tbl := table.New("Host", "Contract", "Cost")
+			for _, r := range results {
+				if r.Err != nil {
+					failed++
+					tbl.AddRow(r.HostKey, r.Err, "-")
 					continue
 				}
-				log.Println("Formed contract:", contractID)
+				spent = spent.Add(r.Cost)
+				tbl.AddRow(r.HostKey, r.ID, r.Cost.HumanString())
+			}
+			tbl.Print()
+			log.Printf("Formed %v of %v contracts for %v", len(results)-failed, len(results), spent.HumanString())
+			if failed > 0 {
+				log.Fatalf("failed to form %v of %v contracts", failed, len(results))
 			}
 		},
 	}
 )
 
-// formContract forms a new contract with the host and adds it to renterd
-func formContract(renterPriv api.PrivateKey, hostPub api.PublicKey, usage, duration uint64) (types.FileContractID, error) {
+// A formResult is the outcome of forming a contract with a host.
+type formResult struct {
+	HostKey    api.PublicKey
+	NetAddress string
+	ID         types.FileContractID
+	Cost       types.Currency
+	Err        error
+
+	quote *contractQuote
+}
+
+// estimateContractCost returns the renter funds needed for the usage over
+// duration blocks with the host and the collateral the host should put up.
+func estimateContractCost(settings rhp.HostSettings, usage usageEstimate, duration uint64) (renterFunds, hostCollateral types.Currency) {
+	q := estimateContract(settings, usage, duration)
+	return q.renterFunds(), q.Collateral
+}
+
+// formContract forms a new contract with the host and adds it to renterd. If
+// hostAddr is empty, the host's address is looked up from the configured host
+// source. It returns the contract's ID and the amount spent forming it. If
+// maxCost is non-zero, formation is aborted before any funds are spent if the
+// cost exceeds it.
+func formContract(renterPriv api.PrivateKey, hostPub api.PublicKey, hostAddr string, usage usageEstimate, duration uint64, maxCost types.Currency) (types.FileContractID, types.Currency, error) {
+	q, err := quoteContract(renterPriv, hostPub, hostAddr, usage, duration)
+	if err != nil {
+		return types.FileContractID{}, types.ZeroCurrency, err
+	}
+	return formQuotedContract(renterPriv, q, maxCost)
+}
+
+// formQuotedContract forms the quoted contract with the host and adds it to
+// renterd. It returns the contract's ID and the amount spent forming it,
+// including the miner fee. If maxCost is non-zero, formation is aborted before
+// any funds are spent if the quoted cost, or the cost with the miner fee
+// renterd adds when funding, exceeds it.
+func formQuotedContract(renterPriv api.PrivateKey, q contractQuote, maxCost types.Currency) (types.FileContractID, types.Currency, error) {
+	if !maxCost.IsZero() && q.Total.Cmp(maxCost) > 0 {
+		return types.FileContractID{}, types.ZeroCurrency, fmt.Errorf("contract cost %v exceeds max cost %v", q.Total.HumanString(), maxCost.HumanString())
+	}
+
+	formTxn := types.Transaction{
+		FileContracts: []types.FileContract{q.contract},
+	}
+
+	// fund the formation transaction
+	toSign, parents, err := renterdClient.WalletFund(&formTxn, q.funding)
+	if err != nil {
+		return types.FileContractID{}, types.ZeroCurrency, fmt.Errorf("failed to fund formation transaction: %w", err)
+	}
+
+	// the miner fee may have changed since the quote
+	cost := q.funding
+	if len(formTxn.MinerFees) != 0 {
+		cost = cost.Add(formTxn.MinerFees[0])
+	}
+	if !maxCost.IsZero() && cost.Cmp(maxCost) > 0 {
+		renterdClient.WalletDiscard(formTxn)
+		return types.FileContractID{}, types.ZeroCurrency, fmt.Errorf("contract cost %v exceeds max cost %v", cost.HumanString(), maxCost.HumanString())
+	}
+
+	// sign the transaction
+	cf := wallet.ExplicitCoveredFields(formTxn)
+	if err := renterdClient.WalletSign(&formTxn, toSign, cf); err != nil {
+		return types.FileContractID{}, types.ZeroCurrency, fmt.Errorf("failed to sign formation transaction: %w", err)
+	}
+
+	// form the contract
+	contract, _, err := renterdClient.RHPForm(renterPriv, q.HostKey, q.NetAddress, append(parents, formTxn))
+	if err != nil {
+		renterdClient.WalletDiscard(formTxn) // formation error discard the inputs, ignore the error
+		return types.FileContractID{}, types.ZeroCurrency, fmt.Errorf("failed to form contract: %w", err)
+	}
+
+	// add the contract to renterd
+	if err := renterdClient.AddContract(contract); err != nil {
+		return types.FileContractID{}, types.ZeroCurrency, fmt.Errorf("failed to add contract: %w", err)
+	} else if err := recordContract(dataDir, contract, q.StartHeight, q.Settings); err != nil {
+		log.Printf("failed to record contract %v: %v", contract.ID(), err)
+	}
+
+	return contract.ID(), cost, nil
+}
+
+// autoFormContracts forms contracts with the n best ranked hosts that the
+// renter does not have an active contract with. If budget is non-zero, the
+// total cost of all formations is limited to it. If maxCost is non-zero,
+// formation with a host is aborted if its cost exceeds it. If confirm is
+// true, the selected hosts are printed and the user is asked to confirm
+// before any funds are spent. It returns the number of contracts formed.
+func autoFormContracts(renterPriv api.PrivateKey, n int, usage usageEstimate, duration uint64, budget, maxCost types.Currency, confirm bool) (int, error) {
+	tip, err := renterdClient.ConsensusTip()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consensus tip: %w", err)
+	}
+
+	contracts, err := renterdClient.Contracts()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get contracts: %w", err)
+	}
+	exclude := make(map[api.PublicKey]bool)
+	for _, c := range contracts {
+		if tip.Height < c.EndHeight() {
+			exclude[c.HostKey()] = true
+		}
+	}
+
+	candidates, err := rankHosts(n, usage, duration, exclude)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Found %v candidate hosts", len(candidates))
+
+	if confirm {
+		selected := candidates
+		if len(selected) > n {
+			selected = selected[:n]
+		}
+		estimate := types.ZeroCurrency
+		tbl := table.New("Host", "Score", "Est. Cost")
+		for _, c := range selected {
+			estimate = estimate.Add(c.Cost)
+			tbl.AddRow(c.PublicKey, fmt.Sprintf("%.4f", c.Score), c.Cost.HumanString())
+		}
+		tbl.Print()
+		if !promptConfirm(fmt.Sprintf("Form up to %v contracts for an estimated %v excluding fees?", n, estimate.HumanString())) {
+			return 0, errors.New("aborted")
+		}
+	}
+
+	var formed int
+	spent := types.ZeroCurrency
+	for _, c := range candidates {
+		if formed == n {
+			break
+		}
+
+		limit := maxCost
+		if !budget.IsZero() {
+			if spent.Cmp(budget) >= 0 {
+				log.Printf("Budget exhausted, %v contracts still needed", n-formed)
+				break
+			} else if remaining := budget.Sub(spent); limit.IsZero() || remaining.Cmp(limit) < 0 {
+				limit = remaining
+			}
+		}
+
+		log.Printf("Forming contract with host %v (score %.4f, est. %v)", c.PublicKey, c.Score, c.Cost.HumanString())
+		id, cost, err := formContract(renterPriv, c.PublicKey, c.NetAddress, usage, duration, limit)
+		if err != nil {
+			log.Printf("failed to form contract with host %v: %v", c.PublicKey, err)
+			continue
+		}
+		spent = spent.Add(cost)
+		formed++
+		log.Printf("Formed contract %v with host %v for %v", id, c.PublicKey, cost.HumanString())
+	}
+	log.Printf("Formed %v contracts for %v", formed, spent.HumanString())
+	return formed, nil
+}
+
+// A fundFunc returns the renter funds and host collateral for a contract with
+// the host lasting duration blocks.
+type fundFunc func(settings rhp.HostSettings, duration uint64) (renterFunds, hostCollateral types.Currency)
+
+// usageFunds returns a fundFunc that funds a contract for the usage.
+func usageFunds(usage usageEstimate) fundFunc {
+	return func(settings rhp.HostSettings, duration uint64) (types.Currency, types.Currency) {
+		return estimateContractCost(settings, usage, duration)
+	}
+}
+
+// refillFunds returns a fundFunc that funds a contract with its current
+// remaining funds plus amount. The host is asked for collateral matching the
+// storage the renter funds could buy, capped at the host's max collateral.
+func refillFunds(c rhp.Contract, amount types.Currency) fundFunc {
+	return func(settings rhp.HostSettings, duration uint64) (types.Currency, types.Currency) {
+		renterFunds := c.RenterFunds().Add(amount)
+		hostCollateral := types.ZeroCurrency
+		if !settings.StoragePrice.IsZero() {
+			hostCollateral = renterFunds.Mul(settings.Collateral).Div(settings.StoragePrice)
+		}
+		if hostCollateral.Cmp(settings.MaxCollateral) > 0 {
+			hostCollateral = settings.MaxCollateral
+		}
+		return renterFunds, hostCollateral
+	}
+}
+
+// renewContract renews a contract with its host, extending it to endHeight
+// and funding it with fund. The data stored in the old contract is carried
+// forward to the new contract, which replaces the old contract in renterd. It
+// returns the renewed contract and the amount spent renewing it. If maxCost is
+// non-zero, the renewal is aborted before any funds are spent if the cost
+// exceeds it.
+func renewContract(renterPriv api.PrivateKey, id types.FileContractID, endHeight uint64, fund fundFunc, maxCost types.Currency) (rhp.Contract, types.Currency, error) {
+	old, err := renterdClient.Contract(id)
+	if err != nil {
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to get contract: %w", err)
+	}
+	hostKey := old.HostKey()
+
 	// get the wallet's address
 	renterAddr, err := renterdClient.WalletAddress()
 	if err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to get wallet address: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to get wallet address: %w", err)
 	}
 
 	// get the current block height
 	tip, err := renterdClient.ConsensusTip()
 	if err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to get consensus tip: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to get consensus tip: %w", err)
+	} else if tip.Height >= uint64(old.Revision.NewWindowStart) {
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("contract proof window has already started at height %v", old.Revision.NewWindowStart)
+	} else if endHeight <= tip.Height {
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("end height %v is not in the future", endHeight)
 	}
 
-	// use the Sia Central API to get the host's net address since there is
-	// no host db at this point.
-	host, err := siaCentralClient.GetHost(hostPub.String())
+	netAddress, err := hostAddress(hostKey)
 	if err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to get host info: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, err
 	}
 
 	// get the host's current settings
-	settings, err := renterdClient.RHPScan(hostPub, host.NetAddress)
+	settings, err := renterdClient.RHPScan(hostKey, netAddress)
 	if err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to scan host: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to scan host: %w", err)
 	}
 
-	uploadCost := settings.UploadBandwidthPrice.Mul64(usage)
-	downloadCost := settings.DownloadBandwidthPrice.Mul64(usage)
-	storageCost := settings.StoragePrice.Mul64(usage).Mul64(uint64(duration))
-	hostCollateral := settings.Collateral.Mul64(usage).Mul64(uint64(duration))
+	renterFunds, hostCollateral := fund(settings, endHeight-tip.Height)
 
-	estimatedCost := settings.ContractPrice.Add(uploadCost).Add(downloadCost).Add(storageCost)
-
-	// prepare the contract for formation
-	fc, cost, err := renterdClient.RHPPrepareForm(renterPriv, hostPub, estimatedCost, renterAddr, hostCollateral, tip.Height+duration, settings)
+	// prepare the renewal, the host's costs for the existing data are
+	// included in the cost
+	fc, cost, finalPayment, err := renterdClient.RHPPrepareRenew(old.Revision, renterPriv, hostKey, renterFunds, renterAddr, hostCollateral, endHeight, settings)
 	if err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to prepare contract: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to prepare renewal: %w", err)
+	} else if !maxCost.IsZero() && cost.Cmp(maxCost) > 0 {
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("renewal cost %v exceeds max cost %v", cost.HumanString(), maxCost.HumanString())
 	}
 
-	formTxn := types.Transaction{
+	renewTxn := types.Transaction{
 		FileContracts: []types.FileContract{fc},
 	}
 
-	// fund the formation transaction
-	toSign, parents, err := renterdClient.WalletFund(&formTxn, cost)
+	// fund the renewal transaction
+	toSign, parents, err := renterdClient.WalletFund(&renewTxn, cost)
 	if err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to fund formation transaction: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to fund renewal transaction: %w", err)
 	}
 
 	// sign the transaction
-	cf := wallet.ExplicitCoveredFields(formTxn)
-	if err := renterdClient.WalletSign(&formTxn, toSign, cf); err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to sign formation transaction: %w", err)
+	cf := wallet.ExplicitCoveredFields(renewTxn)
+	if err := renterdClient.WalletSign(&renewTxn, toSign, cf); err != nil {
+		renterdClient.WalletDiscard(renewTxn)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to sign renewal transaction: %w", err)
 	}
 
-	// form the contract
-	contract, _, err := renterdClient.RHPForm(renterPriv, hostPub, host.NetAddress, append(parents, formTxn))
+	// renew the contract
+	contract, _, err := renterdClient.RHPRenew(renterPriv, hostKey, netAddress, id, append(parents, renewTxn), finalPayment)
 	if err != nil {
-		renterdClient.WalletDiscard(formTxn) // formation error discard the inputs, ignore the error
-		return types.FileContractID{}, fmt.Errorf("failed to form contract: %w", err)
+		renterdClient.WalletDiscard(renewTxn) // renewal error discard the inputs, ignore the error
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to renew contract: %w", err)
 	}
 
-	// add the contract to renterd
+	// add the renewed contract to renterd and archive the old one, it can no
+	// longer be revised
 	if err := renterdClient.AddContract(contract); err != nil {
-		return types.FileContractID{}, fmt.Errorf("failed to add contract: %w", err)
+		return rhp.Contract{}, types.ZeroCurrency, fmt.Errorf("failed to add contract: %w", err)
 	}
-
-	return contract.ID(), nil
+	// renterd does not return the clearing revision signed during the
+	// renewal, so the revision from before the renewal is archived
+	if err := archiveContract(dataDir, old, tip.Height, archiveReasonRenewed); err != nil {
+		log.Printf("failed to archive renewed contract %v: %v", id, err)
+	}
+	if err := recordContract(dataDir, contract, tip.Height, settings); err != nil {
+		log.Printf("failed to record contract %v: %v", contract.ID(), err)
+	}
+	return contract, cost, nil
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+
+	"go.sia.tech/siad/types"
+)
+
+// signatureSize is the estimated encoded size of the signature added to a
+// transaction for each funding input. It is used to estimate the signed size
+// of a transaction.
+const signatureSize = 200
+
+// fee args
+var (
+	feePerByteStr string
+)
+
+// signedSize returns the estimated size of the transaction once each of its
+// inputs is signed.
+func signedSize(txn types.Transaction) int {
+	return txn.MarshalSiaSize() + len(txn.SiacoinInputs)*signatureSize
+}
+
+// minerFee returns the miner fee for a funded transaction. --fee sets the
+// total fee. Otherwise the fee is the rate set by --fee-per-byte, or the rate
+// renterd funded the unfunded transaction at, times the transaction's signed
+// size after the fee is paid from the wallet's change.
+func minerFee(txn types.Transaction, changeIndex, unfundedSize int, changeAddr types.UnlockHash) (types.Currency, error) {
+	if minerFeeStr != "" {
+		fee, err := parseCurrency(minerFeeStr)
+		if err != nil {
+			return types.ZeroCurrency, fmt.Errorf("failed to parse fee: %w", err)
+		}
+		return fee, nil
+	}
+
+	var feePerByte types.Currency
+	if feePerByteStr != "" {
+		var err error
+		feePerByte, err = parseCurrency(feePerByteStr)
+		if err != nil {
+			return types.ZeroCurrency, fmt.Errorf("failed to parse fee per byte: %w", err)
+		}
+	} else if len(txn.MinerFees) != 0 && unfundedSize > 0 {
+		// renterd only charges its recommended rate for the unfunded size
+		feePerByte = txn.MinerFees[0].Div64(uint64(unfundedSize))
+	}
+
+	// paying the fee may add a change output, so the fee is estimated again
+	// with the adjusted transaction
+	fee := feePerByte.Mul64(uint64(signedSize(txn)))
+	txn.SiacoinOutputs = append([]types.SiacoinOutput(nil), txn.SiacoinOutputs...)
+	if err := setMinerFee(&txn, changeIndex, fee, changeAddr); err != nil {
+		return types.ZeroCurrency, err
+	}
+	return feePerByte.Mul64(uint64(signedSize(txn))), nil
+}
+
+// setMinerFee replaces the miner fee of a funded transaction. The difference
+// from the funded fee is taken from or returned to the wallet's change
+// output, which renterd adds after the first changeIndex outputs. If the
+// transaction has no change, a change output to changeAddr is added.
+func setMinerFee(txn *types.Transaction, changeIndex int, fee types.Currency, changeAddr types.UnlockHash) error {
+	funded := types.ZeroCurrency
+	if len(txn.MinerFees) != 0 {
+		funded = txn.MinerFees[0]
+	}
+	hasChange := len(txn.SiacoinOutputs) > changeIndex
+
+	switch fee.Cmp(funded) {
+	case 1:
+		diff := fee.Sub(funded)
+		if !hasChange || txn.SiacoinOutputs[changeIndex].Value.Cmp(diff) <= 0 {
+			return fmt.Errorf("wallet change does not cover the additional miner fee of %v", diff.HumanString())
+		}
+		txn.SiacoinOutputs[changeIndex].Value = txn.SiacoinOutputs[changeIndex].Value.Sub(diff)
+	case -1:
+		diff := funded.Sub(fee)
+		if hasChange {
+			txn.SiacoinOutputs[changeIndex].Value = txn.SiacoinOutputs[changeIndex].Value.Add(diff)
+			break
+		}
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
+			Value:      diff,
+			UnlockHash: changeAddr,
+		})
+	}
+
+	txn.MinerFees = nil
+	if !fee.IsZero() {
+		txn.MinerFees = []types.Currency{fee}
+	}
+	return nil
+}
+
+// fundAndSignTransaction funds the transaction with amount and signs it.
+// renterd funds the miner fee in addition to amount, but only for the
+// unfunded size of the transaction, so the fee is replaced with the one
+// returned by minerFee before signing. It returns the miner fee. The funded
+// inputs are released if the transaction can't be signed.
+func fundAndSignTransaction(txn *types.Transaction, amount types.Currency) (types.Currency, error) {
+	changeIndex := len(txn.SiacoinOutputs)
+	unfundedSize := txn.MarshalSiaSize()
+	toSign, _, err := renterdClient.WalletFund(txn, amount)
+	if err != nil {
+		return types.ZeroCurrency, fmt.Errorf("failed to fund transaction: %w", err)
+	}
+
+	changeAddr, err := renterdClient.WalletAddress()
+	if err != nil {
+		renterdClient.WalletDiscard(*txn)
+		return types.ZeroCurrency, fmt.Errorf("failed to get wallet address: %w", err)
+	}
+
+	fee, err := minerFee(*txn, changeIndex, unfundedSize, changeAddr)
+	if err != nil {
+		renterdClient.WalletDiscard(*txn)
+		return types.ZeroCurrency, err
+	} else if err := setMinerFee(txn, changeIndex, fee, changeAddr); err != nil {
+		renterdClient.WalletDiscard(*txn)
+		return types.ZeroCurrency, err
+	}
+
+	if err := renterdClient.WalletSign(txn, toSign, types.FullCoveredFields); err != nil {
+		renterdClient.WalletDiscard(*txn)
+		return types.ZeroCurrency, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return fee, nil
+}
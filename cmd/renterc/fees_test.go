@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+func TestSetMinerFee(t *testing.T) {
+	recipient := types.UnlockHash{1}
+	changeAddr := types.UnlockHash{2}
+	sc := types.SiacoinPrecision
+
+	tests := []struct {
+		name    string
+		change  types.Currency // zero for no change output
+		funded  types.Currency
+		fee     types.Currency
+		outputs []types.SiacoinOutput
+		err     bool
+	}{
+		{"unchanged", sc.Mul64(5), sc, sc, []types.SiacoinOutput{
+			{Value: sc.Mul64(10), UnlockHash: recipient},
+			{Value: sc.Mul64(5), UnlockHash: changeAddr},
+		}, false},
+		{"raise", sc.Mul64(5), sc, sc.Mul64(3), []types.SiacoinOutput{
+			{Value: sc.Mul64(10), UnlockHash: recipient},
+			{Value: sc.Mul64(3), UnlockHash: changeAddr},
+		}, false},
+		{"raise beyond change", sc.Mul64(5), sc, sc.Mul64(6), nil, true},
+		{"raise without change", types.ZeroCurrency, sc, sc.Mul64(2), nil, true},
+		{"lower", sc.Mul64(5), sc.Mul64(3), sc, []types.SiacoinOutput{
+			{Value: sc.Mul64(10), UnlockHash: recipient},
+			{Value: sc.Mul64(7), UnlockHash: changeAddr},
+		}, false},
+		{"lower without change", types.ZeroCurrency, sc.Mul64(3), sc, []types.SiacoinOutput{
+			{Value: sc.Mul64(10), UnlockHash: recipient},
+			{Value: sc.Mul64(2), UnlockHash: changeAddr},
+		}, false},
+		{"zero", sc.Mul64(5), sc, types.ZeroCurrency, []types.SiacoinOutput{
+			{Value: sc.Mul64(10), UnlockHash: recipient},
+			{Value: sc.Mul64(6), UnlockHash: changeAddr},
+		}, false},
+	}
+	for _, test := range tests {
+		txn := types.Transaction{
+			SiacoinOutputs: []types.SiacoinOutput{{Value: sc.Mul64(10), UnlockHash: recipient}},
+			MinerFees:      []types.Currency{test.funded},
+		}
+		if !test.change.IsZero() {
+			txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{Value: test.change, UnlockHash: changeAddr})
+		}
+
+		err := setMinerFee(&txn, 1, test.fee, changeAddr)
+		if test.err {
+			if err == nil {
+				t.Fatalf("%v: expected an error", test.name)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		if !reflect.DeepEqual(txn.SiacoinOutputs, test.outputs) {
+			t.Fatalf("%v: expected outputs %v, got %v", test.name, test.outputs, txn.SiacoinOutputs)
+		} else if test.fee.IsZero() && txn.MinerFees != nil {
+			t.Fatalf("%v: expected no miner fees, got %v", test.name, txn.MinerFees)
+		} else if !test.fee.IsZero() && (len(txn.MinerFees) != 1 || !txn.MinerFees[0].Equals(test.fee)) {
+			t.Fatalf("%v: expected miner fee %v, got %v", test.name, test.fee, txn.MinerFees)
+		}
+	}
+}
+
+func TestMinerFee(t *testing.T) {
+	defer func() { minerFeeStr, feePerByteStr = "", "" }()
+
+	changeAddr := types.UnlockHash{2}
+	unfunded := types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{Value: types.SiacoinPrecision, UnlockHash: types.UnlockHash{1}}},
+	}
+	unfundedSize := unfunded.MarshalSiaSize()
+
+	// renterd funds the fee at its recommended rate for the unfunded size
+	rate := types.NewCurrency64(100)
+	funded := unfunded
+	funded.SiacoinInputs = make([]types.SiacoinInput, 3)
+	funded.MinerFees = []types.Currency{rate.Mul64(uint64(unfundedSize))}
+
+	// the expected fee covers the signed size including the change output
+	// added to return the difference
+	expected := func(feePerByte types.Currency) types.Currency {
+		txn := funded
+		txn.SiacoinOutputs = append([]types.SiacoinOutput(nil), funded.SiacoinOutputs...)
+		if err := setMinerFee(&txn, 1, feePerByte.Mul64(uint64(signedSize(txn))), changeAddr); err != nil {
+			t.Fatal(err)
+		}
+		return feePerByte.Mul64(uint64(signedSize(txn)))
+	}
+
+	// without a change output, the fee can only be lowered
+	minerFeeStr, feePerByteStr = "", "1H"
+	fee, err := minerFee(funded, 1, unfundedSize, changeAddr)
+	if err != nil {
+		t.Fatal(err)
+	} else if !fee.Equals(expected(types.NewCurrency64(1))) {
+		t.Fatalf("expected fee %v, got %v", expected(types.NewCurrency64(1)), fee)
+	}
+
+	// with change, renterd's rate is applied to the signed size
+	funded.SiacoinOutputs = append(funded.SiacoinOutputs, types.SiacoinOutput{Value: types.SiacoinPrecision, UnlockHash: changeAddr})
+	minerFeeStr, feePerByteStr = "", ""
+	fee, err = minerFee(funded, 1, unfundedSize, changeAddr)
+	if err != nil {
+		t.Fatal(err)
+	} else if !fee.Equals(expected(rate)) {
+		t.Fatalf("expected fee %v, got %v", expected(rate), fee)
+	} else if fee.Cmp(funded.MinerFees[0]) <= 0 {
+		t.Fatalf("expected fee %v to exceed the unfunded fee %v", fee, funded.MinerFees[0])
+	}
+
+	minerFeeStr, feePerByteStr = "1SC", "1H"
+	fee, err = minerFee(funded, 1, unfundedSize, changeAddr)
+	if err != nil {
+		t.Fatal(err)
+	} else if !fee.Equals(types.SiacoinPrecision) {
+		t.Fatalf("expected fee %v, got %v", types.SiacoinPrecision, fee)
+	}
+}
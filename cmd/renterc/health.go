@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/slab"
+)
+
+var (
+	healthCmd = &cobra.Command{
+		Use:   "health",
+		Short: "report the redundancy of objects",
+		Long: `renterc objects health [key]
+
+Counts the shards of every slab that are stored on hosts with usable contracts and compares them to the number of shards required to recover the slab. Without a key, a summary of every object is printed. With a key, each slab of the object is listed.
+
+An object is degraded if any slab has lost shards, at risk if any slab has only the minimum number of healthy shards left, and unrecoverable if any slab has fewer.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("at most one object key is allowed")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			dc, err := newDownloadContracts(renterPriv)
+			if err != nil {
+				log.Fatalln("failed to get contracts:", err)
+			}
+
+			// print the health of each slab of a single object
+			if len(args) == 1 {
+				obj, err := renterdClient.Object(normalizeKey(args[0]))
+				if err != nil {
+					log.Fatalln("failed to get object:", err)
+				}
+
+				tbl := table.New("Slab", "Healthy", "Min", "Total", "Redundancy", "Status")
+				for i, s := range obj.Slabs {
+					healthy := healthyShards(dc, s.Slab)
+					tbl.AddRow(i+1, healthy, s.MinShards, len(s.Shards), fmt.Sprintf("%.2fx", redundancy(healthy, s.Slab)), slabStatus(healthy, s.Slab))
+				}
+				tbl.Print()
+				return
+			}
+
+			keys, err := objectKeys("")
+			if err != nil {
+				log.Fatalln("failed to get objects:", err)
+			}
+
+			var atRisk, unrecoverable, failed int
+			tbl := table.New("Object", "Slabs", "Min Redundancy", "Status")
+			for _, key := range keys {
+				// report objects that can't be fetched instead of stopping
+				// the summary
+				obj, err := renterdClient.Object(key)
+				if err != nil {
+					tbl.AddRow(key, "-", "-", fmt.Sprintf("error: %v", err))
+					failed++
+					continue
+				}
+
+				// the object is only as healthy as its least healthy slab
+				status := "healthy"
+				var minRedundancy float64
+				for i, s := range obj.Slabs {
+					healthy := healthyShards(dc, s.Slab)
+					if r := redundancy(healthy, s.Slab); i == 0 || r < minRedundancy {
+						minRedundancy = r
+					}
+					if ss := slabStatus(healthy, s.Slab); statusSeverity[ss] > statusSeverity[status] {
+						status = ss
+					}
+				}
+
+				switch status {
+				case "at risk":
+					atRisk++
+				case "unrecoverable":
+					unrecoverable++
+				}
+				tbl.AddRow(key, len(obj.Slabs), fmt.Sprintf("%.2fx", minRedundancy), status)
+			}
+			tbl.Print()
+			log.Printf("%v objects, %v at risk, %v unrecoverable, %v failed to load", len(keys), atRisk, unrecoverable, failed)
+		},
+	}
+)
+
+// statusSeverity orders the slab statuses from least to most severe.
+var statusSeverity = map[string]int{
+	"healthy":       0,
+	"degraded":      1,
+	"at risk":       2,
+	"unrecoverable": 3,
+}
+
+// redundancy returns the effective redundancy of a slab with healthy usable
+// shards, the number of healthy shards divided by the minimum required.
+func redundancy(healthy int, s slab.Slab) float64 {
+	if s.MinShards == 0 {
+		return 0
+	}
+	return float64(healthy) / float64(s.MinShards)
+}
+
+// slabStatus returns the health status of a slab with healthy usable shards.
+func slabStatus(healthy int, s slab.Slab) string {
+	switch {
+	case healthy < int(s.MinShards):
+		return "unrecoverable"
+	case healthy == int(s.MinShards) && healthy < len(s.Shards):
+		return "at risk"
+	case healthy < len(s.Shards):
+		return "degraded"
+	default:
+		return "healthy"
+	}
+}
@@ -0,0 +1,758 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/siacentral/apisdkgo/sia"
+	sctypes "github.com/siacentral/apisdkgo/sia/types"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/siad/types"
+)
+
+// host sources
+const (
+	// hostSourceAuto uses renterd's hostdb to look up addresses and Sia
+	// Central to list hosts, falling back to the other if one fails.
+	hostSourceAuto = "auto"
+	// hostSourceRenterd only uses renterd's hostdb.
+	hostSourceRenterd = "renterd"
+	// hostSourceSiaCentral only uses the Sia Central API.
+	hostSourceSiaCentral = "siacentral"
+)
+
+// host args
+var (
+	hostSource           string
+	hostMaxContractPrice string
+	hostMaxStoragePrice  string
+	hostMaxIngressPrice  string
+	hostMaxEgressPrice   string
+	hostMinUptime        float64
+	hostMinAge           string
+	hostAccepting        bool
+	hostBenchmarked      bool
+	hostSortBy           string
+	hostSortDesc         bool
+	hostLimit            int
+	hostCountries        []string
+)
+
+// hostLimits are the requirements a host must meet to be listed. Zero prices
+// are not limited.
+type hostLimits struct {
+	MaxContractPrice types.Currency
+	// MaxStoragePrice is per byte per block.
+	MaxStoragePrice types.Currency
+	// MaxIngressPrice and MaxEgressPrice are per byte.
+	MaxIngressPrice    types.Currency
+	MaxEgressPrice     types.Currency
+	MinUptime          float64
+	MinAge             time.Duration
+	AcceptingContracts bool
+	Benchmarked        bool
+	// Countries are upper-case ISO 3166-1 alpha-2 country codes. Empty
+	// allows every country.
+	Countries []string
+}
+
+// A siaCentralHost is a host listed by Sia Central, including the country
+// code the SDK does not decode.
+type siaCentralHost struct {
+	sctypes.HostDetails
+	CountryCode string `json:"country_code"`
+}
+
+// settingsLess returns a comparison of a host setting. Hosts without settings
+// sort last.
+func settingsLess(value func(s *sctypes.HostExternalSettings) types.Currency) func(a, b siaCentralHost) bool {
+	return func(a, b siaCentralHost) bool {
+		if a.Settings == nil {
+			return false
+		} else if b.Settings == nil {
+			return true
+		}
+		return value(a.Settings).Cmp(value(b.Settings)) < 0
+	}
+}
+
+// hostSortFuncs compares hosts by each column of the hosts table.
+var hostSortFuncs = map[string]func(a, b siaCentralHost) bool{
+	"key": func(a, b siaCentralHost) bool { return a.PublicKey < b.PublicKey },
+	"contract": settingsLess(func(s *sctypes.HostExternalSettings) types.Currency {
+		return s.ContractPrice
+	}),
+	"storage": settingsLess(func(s *sctypes.HostExternalSettings) types.Currency {
+		return s.StoragePrice
+	}),
+	"ingress": settingsLess(func(s *sctypes.HostExternalSettings) types.Currency {
+		return s.UploadBandwidthPrice
+	}),
+	"egress": settingsLess(func(s *sctypes.HostExternalSettings) types.Currency {
+		return s.DownloadBandwidthPrice
+	}),
+	// older hosts were first seen earlier
+	"age":     func(a, b siaCentralHost) bool { return a.FirstSeenTimestamp.After(b.FirstSeenTimestamp) },
+	"uptime":  func(a, b siaCentralHost) bool { return a.EstimatedUptime < b.EstimatedUptime },
+	"country": func(a, b siaCentralHost) bool { return a.CountryCode < b.CountryCode },
+}
+
+var (
+	hostsCmd = &cobra.Command{
+		Use:   "hosts",
+		Short: "get a list of hosts",
+		Long: `renterc hosts [flags]
+
+Lists hosts that are good candidates for forming contracts. Hosts are listed from Sia Central by default. Use --host-source renterd to list the hosts in renterd's hostdb instead, for example on a private network. With the default --host-source auto, renterd's hostdb is used if Sia Central cannot be reached.
+
+Sia Central hosts are filtered by the price, uptime, age, country and status flags. Storage prices are per TB per month and ingress and egress prices are per TB. Use --sort with one of key, contract, storage, ingress, egress, age, uptime or country to sort the hosts and --limit to only list the first hosts. The renterd hostdb does not store host settings or locations, so only --limit applies to it and --country or --sort country fail.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if _, ok := hostSortFuncs[hostSortBy]; hostSortBy != "" && !ok {
+				return fmt.Errorf("unknown sort column %q", hostSortBy)
+			} else if hostLimit < 0 {
+				return errors.New("--limit must be positive")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			limits, err := parseHostLimits()
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			switch hostSource {
+			case hostSourceSiaCentral:
+				if err := printSiaCentralHosts(limits); err != nil {
+					log.Fatalln("failed to get hosts:", err)
+				}
+			case hostSourceRenterd:
+				if err := printRenterdHosts(limits); err != nil {
+					log.Fatalln("failed to get hosts:", err)
+				}
+			case hostSourceAuto:
+				if err := printSiaCentralHosts(limits); err != nil {
+					log.Println("failed to get hosts from Sia Central, using renterd:", err)
+					if err := printRenterdHosts(limits); err != nil {
+						log.Fatalln("failed to get hosts:", err)
+					}
+				}
+			default:
+				log.Fatalf("unknown host source %q", hostSource)
+			}
+		},
+	}
+
+	scanHostsCmd = &cobra.Command{
+		Use:   "scan",
+		Short: "scan host(s) and check their settings",
+		Long: `renterc hosts scan [flags] <host public key 1>[@<address>] [host public key 2 ...]
+
+Scans the hosts concurrently and prints the round-trip latency and current settings of each host. Prices are checked against the --max-contract-price, --max-storage-price, --max-ingress-price and --max-egress-price limits. Each host's address is looked up from --host-source unless it is given after the public key.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("at least one host is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			limits, err := parseHostLimits()
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			results := make([]hostScan, len(args))
+			forEachConcurrent(len(args), maxConcurrentScans, func(i int) {
+				results[i] = scanHost(args[i])
+			})
+
+			var failed int
+			for _, result := range results {
+				if !printHostScan(result, limits) {
+					failed++
+				}
+			}
+			if failed > 0 {
+				log.Fatalf("%v of %v hosts failed the scan or price check", failed, len(args))
+			}
+		},
+	}
+)
+
+// A hostScan is the result of scanning a host.
+type hostScan struct {
+	Arg        string
+	PublicKey  api.PublicKey
+	NetAddress string
+	Latency    time.Duration
+	Settings   rhp.HostSettings
+	Err        error
+}
+
+// scanHost scans the host and measures the round-trip latency of the scan.
+func scanHost(arg string) (result hostScan) {
+	result.Arg = arg
+	hostKey, addr, err := parseHostArg(arg)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.PublicKey = hostKey
+	if addr == "" {
+		addr, err = hostAddress(hostKey)
+		if err != nil {
+			result.Err = err
+			return
+		}
+	}
+	result.NetAddress = addr
+
+	start := time.Now()
+	result.Settings, result.Err = renterdClient.RHPScan(hostKey, addr)
+	result.Latency = time.Since(start)
+	return
+}
+
+// checkPrice returns the result of comparing a price to its limit.
+func checkPrice(price, max types.Currency) string {
+	switch {
+	case max.IsZero():
+		return "-"
+	case price.Cmp(max) > 0:
+		return "FAIL"
+	default:
+		return "pass"
+	}
+}
+
+// printHostScan prints the scan result and returns true if the scan succeeded
+// and the host's settings pass the limits.
+func printHostScan(result hostScan, limits hostLimits) bool {
+	if result.Err != nil {
+		log.Printf("Host %v: scan failed: %v", result.Arg, result.Err)
+		return false
+	}
+
+	settings := result.Settings
+	log.Printf("Host %v (%v): %v latency", result.PublicKey, result.NetAddress, result.Latency.Round(time.Millisecond))
+
+	passed := true
+	check := func(price, max types.Currency) string {
+		c := checkPrice(price, max)
+		if c == "FAIL" {
+			passed = false
+		}
+		return c
+	}
+	accepting := "-"
+	if limits.AcceptingContracts {
+		accepting = "pass"
+		if !settings.AcceptingContracts {
+			accepting = "FAIL"
+			passed = false
+		}
+	}
+
+	limitStr := func(c types.Currency, scale uint64, unit string) string {
+		if c.IsZero() {
+			return "-"
+		}
+		return c.Mul64(scale).HumanString() + unit
+	}
+
+	tbl := table.New("Setting", "Value", "Limit", "Check")
+	tbl.AddRow("Version", settings.Version, "-", "-")
+	tbl.AddRow("Accepting Contracts", settings.AcceptingContracts, limits.AcceptingContracts, accepting)
+	tbl.AddRow("Contract Price", settings.ContractPrice.HumanString(), limitStr(limits.MaxContractPrice, 1, ""), check(settings.ContractPrice, limits.MaxContractPrice))
+	tbl.AddRow("Storage Price", settings.StoragePrice.Mul64(1e12).Mul64(4320).HumanString()+"/TBmo", limitStr(limits.MaxStoragePrice, 1e12*4320, "/TBmo"), check(settings.StoragePrice, limits.MaxStoragePrice))
+	tbl.AddRow("Ingress Price", settings.UploadBandwidthPrice.Mul64(1e12).HumanString()+"/TB", limitStr(limits.MaxIngressPrice, 1e12, "/TB"), check(settings.UploadBandwidthPrice, limits.MaxIngressPrice))
+	tbl.AddRow("Egress Price", settings.DownloadBandwidthPrice.Mul64(1e12).HumanString()+"/TB", limitStr(limits.MaxEgressPrice, 1e12, "/TB"), check(settings.DownloadBandwidthPrice, limits.MaxEgressPrice))
+	tbl.AddRow("Base RPC Price", settings.BaseRPCPrice.HumanString(), "-", "-")
+	tbl.AddRow("Sector Access Price", settings.SectorAccessPrice.HumanString(), "-", "-")
+	tbl.AddRow("Collateral", settings.Collateral.Mul64(1e12).Mul64(4320).HumanString()+"/TBmo", "-", "-")
+	tbl.AddRow("Max Collateral", settings.MaxCollateral.HumanString(), "-", "-")
+	tbl.AddRow("Max Duration", fmt.Sprintf("%v blocks", settings.MaxDuration), "-", "-")
+	tbl.AddRow("Window Size", fmt.Sprintf("%v blocks", settings.WindowSize), "-", "-")
+	tbl.AddRow("Remaining Storage", fmt.Sprintf("%v / %v bytes", settings.RemainingStorage, settings.TotalStorage), "-", "-")
+	tbl.Print()
+	fmt.Println()
+	return passed
+}
+
+// defaultHostFilter returns the filter used to find hosts that are good
+// candidates for forming contracts.
+func defaultHostFilter() sia.HostFilter {
+	acceptingContracts, benchmarked := true, true
+	maxContractPrice := types.SiacoinPrecision.Div64(2)
+	var minUptime float32 = 0.85
+	return sia.HostFilter{
+		AcceptingContracts: &acceptingContracts,
+		MaxContractPrice:   &maxContractPrice,
+		MinUptime:          &minUptime,
+		Benchmarked:        &benchmarked,
+	}
+}
+
+// parseHostLimits parses the host filter flags.
+func parseHostLimits() (limits hostLimits, err error) {
+	parsePrice := func(s, name string, per uint64) (types.Currency, error) {
+		if s == "" {
+			return types.ZeroCurrency, nil
+		}
+		c, err := parseCurrency(s)
+		if err != nil {
+			return types.ZeroCurrency, fmt.Errorf("failed to parse %v: %w", name, err)
+		}
+		return c.Div64(per), nil
+	}
+
+	if limits.MaxContractPrice, err = parsePrice(hostMaxContractPrice, "max contract price", 1); err != nil {
+		return hostLimits{}, err
+	} else if limits.MaxStoragePrice, err = parsePrice(hostMaxStoragePrice, "max storage price", 1e12*4320); err != nil {
+		return hostLimits{}, err
+	} else if limits.MaxIngressPrice, err = parsePrice(hostMaxIngressPrice, "max ingress price", 1e12); err != nil {
+		return hostLimits{}, err
+	} else if limits.MaxEgressPrice, err = parsePrice(hostMaxEgressPrice, "max egress price", 1e12); err != nil {
+		return hostLimits{}, err
+	}
+
+	if hostMinAge != "" {
+		blocks, err := parseBlockDurStr(hostMinAge)
+		if err != nil {
+			return hostLimits{}, fmt.Errorf("failed to parse min age: %w", err)
+		}
+		limits.MinAge = time.Duration(blocks) * 10 * time.Minute
+	}
+	limits.MinUptime = hostMinUptime
+	limits.AcceptingContracts = hostAccepting
+	limits.Benchmarked = hostBenchmarked
+	for _, country := range hostCountries {
+		limits.Countries = append(limits.Countries, strings.ToUpper(country))
+	}
+	return limits, nil
+}
+
+// query returns the Sia Central host filter query for the limits. The age
+// and country limits are checked by allows.
+func (l hostLimits) query() url.Values {
+	query := make(url.Values)
+	if l.AcceptingContracts {
+		query.Set("acceptcontracts", "true")
+	}
+	if l.Benchmarked {
+		query.Set("benchmarked", "true")
+	}
+	if !l.MaxContractPrice.IsZero() {
+		query.Set("maxcontractprice", l.MaxContractPrice.String())
+	}
+	if !l.MaxStoragePrice.IsZero() {
+		query.Set("maxstorageprice", l.MaxStoragePrice.String())
+	}
+	if !l.MaxIngressPrice.IsZero() {
+		query.Set("maxuploadprice", l.MaxIngressPrice.String())
+	}
+	if !l.MaxEgressPrice.IsZero() {
+		query.Set("maxdownloadprice", l.MaxEgressPrice.String())
+	}
+	if l.MinUptime > 0 {
+		query.Set("minuptime", fmt.Sprintf("%f", l.MinUptime))
+	}
+	return query
+}
+
+// allows returns true if the host meets the age and country limits.
+func (l hostLimits) allows(host siaCentralHost) bool {
+	if l.MinAge > 0 && time.Since(host.FirstSeenTimestamp) < l.MinAge {
+		return false
+	} else if len(l.Countries) == 0 {
+		return true
+	}
+	for _, country := range l.Countries {
+		if strings.EqualFold(host.CountryCode, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSiaCentralHosts returns the active hosts from Sia Central matching the
+// query. The hosts are requested directly since the SDK drops their country.
+func getSiaCentralHosts(query url.Values) ([]siaCentralHost, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(siaCentralClient.BaseAddress + "/hosts?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var hostsResp struct {
+		Message string           `json:"message"`
+		Type    string           `json:"type"`
+		Hosts   []siaCentralHost `json:"hosts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&hostsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode hosts: %w", err)
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 || hostsResp.Type != "success" {
+		return nil, errors.New(hostsResp.Message)
+	}
+	return hostsResp.Hosts, nil
+}
+
+// printSiaCentralHosts prints the active hosts from Sia Central that meet the
+// limits.
+func printSiaCentralHosts(limits hostLimits) error {
+	// get the list of hosts
+	all, err := getSiaCentralHosts(limits.query())
+	if err != nil {
+		return err
+	}
+
+	// without any country codes the country flags can't be applied
+	if len(limits.Countries) != 0 || hostSortBy == "country" {
+		var known bool
+		for _, host := range all {
+			if host.CountryCode != "" {
+				known = true
+				break
+			}
+		}
+		if len(all) != 0 && !known {
+			return errors.New("Sia Central did not report a country for any host")
+		}
+	}
+
+	var hosts []siaCentralHost
+	for _, host := range all {
+		if limits.allows(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	if less, ok := hostSortFuncs[hostSortBy]; ok {
+		sort.SliceStable(hosts, func(i, j int) bool {
+			if hostSortDesc {
+				return less(hosts[j], hosts[i])
+			}
+			return less(hosts[i], hosts[j])
+		})
+	}
+	if hostLimit > 0 && len(hosts) > hostLimit {
+		hosts = hosts[:hostLimit]
+	}
+
+	tbl := table.New("#", "Public Key", "Contract Price", "Storage Price", "Ingress Price", "Egress Price", "First Seen", "Est. Uptime", "Country")
+	for i, host := range hosts {
+		contractPrice, storagePrice, uploadPrice, downloadPrice := "-", "-", "-", "-"
+		if host.Settings != nil {
+			contractPrice = host.Settings.ContractPrice.HumanString()
+			storagePrice = fmt.Sprintf("%v/TBmo", host.Settings.StoragePrice.Mul64(1e12).Mul64(4320).HumanString())
+			uploadPrice = fmt.Sprintf("%v/TB", host.Settings.UploadBandwidthPrice.Mul64(1e12).HumanString())
+			downloadPrice = fmt.Sprintf("%v/TB", host.Settings.DownloadBandwidthPrice.Mul64(1e12).HumanString())
+		}
+		country := host.CountryCode
+		if country == "" {
+			country = "-"
+		}
+		tbl.AddRow(i+1, host.PublicKey, contractPrice, storagePrice, uploadPrice, downloadPrice, host.FirstSeenTimestamp.Local().Format(time.RFC822), fmt.Sprintf("%.2f%%", host.EstimatedUptime), country)
+	}
+	tbl.Print()
+	return nil
+}
+
+// printRenterdHosts prints the hosts in renterd's hostdb. The hostdb does not
+// store host settings, use renterc hosts scan to check a host's prices. It
+// fails if hosts should be filtered or sorted by country, since the hostdb
+// does not store host locations either.
+func printRenterdHosts(limits hostLimits) error {
+	if len(limits.Countries) != 0 || hostSortBy == "country" {
+		return errors.New("renterd's hostdb has no host countries, use --host-source siacentral to filter or sort by country")
+	}
+
+	hosts, err := renterdClient.Hosts()
+	if err != nil {
+		return err
+	}
+
+	tbl := table.New("#", "Public Key", "Net Address", "Last Announced")
+	var n int
+	for _, host := range hosts {
+		if len(host.Announcements) == 0 {
+			continue
+		} else if hostLimit > 0 && n >= hostLimit {
+			break
+		}
+		n++
+		announcement := host.Announcements[len(host.Announcements)-1]
+		tbl.AddRow(n, host.PublicKey, announcement.NetAddress, announcement.Timestamp.Local().Format(time.RFC822))
+	}
+	tbl.Print()
+	return nil
+}
+
+// renterdHostAddress returns the host's most recently announced net address
+// from the renterd hostdb.
+func renterdHostAddress(hostKey api.PublicKey) (string, error) {
+	host, err := renterdClient.Host(hostKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get host %v info: %w", hostKey, err)
+	} else if len(host.Announcements) == 0 {
+		return "", fmt.Errorf("host %v has no announcements", hostKey)
+	}
+	return host.Announcements[len(host.Announcements)-1].NetAddress, nil
+}
+
+// siaCentralHostAddress returns the host's net address from Sia Central.
+func siaCentralHostAddress(hostKey api.PublicKey) (string, error) {
+	host, err := siaCentralClient.GetHost(hostKey.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to get host %v info from Sia Central: %w", hostKey, err)
+	}
+	return host.NetAddress, nil
+}
+
+// hostAddress returns the host's net address from the configured host
+// source.
+func hostAddress(hostKey api.PublicKey) (string, error) {
+	switch hostSource {
+	case hostSourceRenterd:
+		return renterdHostAddress(hostKey)
+	case hostSourceSiaCentral:
+		return siaCentralHostAddress(hostKey)
+	case hostSourceAuto:
+		addr, err := renterdHostAddress(hostKey)
+		if err == nil {
+			return addr, nil
+		}
+		// fall back to Sia Central if renterd doesn't know the host
+		addr, scErr := siaCentralHostAddress(hostKey)
+		if scErr != nil {
+			return "", fmt.Errorf("%v; %w", err, scErr)
+		}
+		return addr, nil
+	default:
+		return "", fmt.Errorf("unknown host source %q", hostSource)
+	}
+}
+
+// A candidateHost is a host contracts can be formed with, listed from the
+// configured host source.
+type candidateHost struct {
+	PublicKey  api.PublicKey
+	NetAddress string
+	// Uptime is the host's estimated uptime between 0 and 1.
+	Uptime    float64
+	FirstSeen time.Time
+	// Settings are the host's last known settings, nil if the source has
+	// none.
+	Settings *rhp.HostSettings
+}
+
+// siaCentralCandidates returns the active hosts from Sia Central that match
+// defaultHostFilter.
+func siaCentralCandidates() ([]candidateHost, error) {
+	hosts, err := siaCentralClient.GetActiveHosts(defaultHostFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []candidateHost
+	for _, host := range hosts {
+		var hostKey api.PublicKey
+		if err := hostKey.UnmarshalText([]byte(host.PublicKey)); err != nil {
+			continue
+		}
+		c := candidateHost{
+			PublicKey:  hostKey,
+			NetAddress: host.NetAddress,
+			// Sia Central reports the estimated uptime as a percentage
+			Uptime:    float64(host.EstimatedUptime) / 100,
+			FirstSeen: host.FirstSeenTimestamp,
+		}
+		if host.Settings != nil {
+			c.Settings = &rhp.HostSettings{
+				AcceptingContracts:     host.Settings.AcceptingContracts,
+				ContractPrice:          host.Settings.ContractPrice,
+				StoragePrice:           host.Settings.StoragePrice,
+				UploadBandwidthPrice:   host.Settings.UploadBandwidthPrice,
+				DownloadBandwidthPrice: host.Settings.DownloadBandwidthPrice,
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// renterdCandidates returns the announced hosts in renterd's hostdb, ordered
+// by renterd's host score. The hostdb does not store settings or uptime, so
+// every host is assumed to be online and is as old as its first
+// announcement.
+func renterdCandidates() ([]candidateHost, error) {
+	hosts, err := renterdClient.Hosts()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(hosts, func(i, j int) bool { return hosts[i].Score > hosts[j].Score })
+
+	var candidates []candidateHost
+	for _, host := range hosts {
+		if len(host.Announcements) == 0 {
+			continue
+		}
+		candidates = append(candidates, candidateHost{
+			PublicKey:  host.PublicKey,
+			NetAddress: host.NetAddress(),
+			Uptime:     1,
+			FirstSeen:  host.Announcements[0].Timestamp,
+		})
+	}
+	return candidates, nil
+}
+
+// candidateHosts returns the hosts to form contracts with from the configured
+// host source. With hostSourceAuto, renterd's hostdb is used if Sia Central
+// cannot be reached.
+func candidateHosts() ([]candidateHost, error) {
+	switch hostSource {
+	case hostSourceSiaCentral:
+		return siaCentralCandidates()
+	case hostSourceRenterd:
+		return renterdCandidates()
+	case hostSourceAuto:
+		candidates, err := siaCentralCandidates()
+		if err == nil {
+			return candidates, nil
+		}
+		log.Println("failed to get hosts from Sia Central, using renterd:", err)
+		return renterdCandidates()
+	default:
+		return nil, fmt.Errorf("unknown host source %q", hostSource)
+	}
+}
+
+// parseHostArg parses a host argument of the form <public key>[@<address>].
+// The address is empty if it was not specified.
+func parseHostArg(arg string) (hostKey api.PublicKey, addr string, err error) {
+	key, addr, _ := strings.Cut(arg, "@")
+	if err := hostKey.UnmarshalText([]byte(key)); err != nil {
+		return api.PublicKey{}, "", fmt.Errorf("failed to parse host key %v: %w", key, err)
+	} else if strings.Contains(arg, "@") && addr == "" {
+		return api.PublicKey{}, "", errors.New("host address must not be empty")
+	}
+	return hostKey, addr, nil
+}
+
+// scoring parameters
+const (
+	// scanCandidatesFactor is the number of candidates, relative to the
+	// number of contracts wanted, that are scanned before ranking.
+	scanCandidatesFactor = 3
+	// maxConcurrentScans is the maximum number of hosts scanned at once.
+	maxConcurrentScans = 10
+	// matureHostAge is the age after which a host no longer receives an age
+	// penalty.
+	matureHostAge = 90 * 24 * time.Hour
+)
+
+// A scoredHost is a candidate host for contract formation.
+type scoredHost struct {
+	PublicKey  api.PublicKey
+	NetAddress string
+	Uptime     float64
+	FirstSeen  time.Time
+	Settings   rhp.HostSettings
+	Cost       types.Currency
+	Score      float64
+}
+
+// currencyFloat converts c to a float64 number of siacoins.
+func currencyFloat(c types.Currency) float64 {
+	f, _ := new(big.Rat).SetFrac(c.Big(), types.SiacoinPrecision.Big()).Float64()
+	return f
+}
+
+// scoreHost returns the host's score for a contract costing cost. uptime is
+// between 0 and 1. Cheaper, more reliable and older hosts score higher. Hosts
+// younger than matureHostAge are penalized by up to half their score.
+func scoreHost(cost types.Currency, uptime float64, firstSeen time.Time) float64 {
+	age := float64(time.Since(firstSeen)) / float64(matureHostAge)
+	if age > 1 {
+		age = 1
+	} else if age < 0 {
+		age = 0
+	}
+	return uptime * (0.5 + 0.5*age) / (1 + currencyFloat(cost))
+}
+
+// rankHosts returns candidate hosts for n contracts funded for the usage over
+// duration blocks, best first. Hosts in exclude are skipped. Candidates from
+// the configured host source are first ranked by their last known prices,
+// then the best are scanned and re-ranked by their live settings. Candidates
+// without known prices, such as every host in renterd's hostdb, keep the
+// source's order behind those with prices. Hosts that fail the scan or are
+// not accepting contracts are dropped.
+func rankHosts(n int, usage usageEstimate, duration uint64, exclude map[api.PublicKey]bool) ([]scoredHost, error) {
+	hosts, err := candidateHosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate hosts: %w", err)
+	}
+
+	var candidates []scoredHost
+	for _, host := range hosts {
+		if exclude[host.PublicKey] {
+			continue
+		} else if host.Settings != nil && !host.Settings.AcceptingContracts {
+			continue
+		}
+		c := scoredHost{
+			PublicKey:  host.PublicKey,
+			NetAddress: host.NetAddress,
+			Uptime:     host.Uptime,
+			FirstSeen:  host.FirstSeen,
+		}
+		if host.Settings != nil {
+			c.Cost, _ = estimateContractCost(*host.Settings, usage, duration)
+			c.Score = scoreHost(c.Cost, c.Uptime, c.FirstSeen)
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > n*scanCandidatesFactor {
+		candidates = candidates[:n*scanCandidatesFactor]
+	}
+
+	// scan the best candidates and re-score them with their current settings
+	scanned := make([]bool, len(candidates))
+	forEachConcurrent(len(candidates), maxConcurrentScans, func(i int) {
+		c := &candidates[i]
+		settings, err := renterdClient.RHPScan(c.PublicKey, c.NetAddress)
+		if err != nil {
+			log.Printf("Skipping host %v, scan failed: %v", c.PublicKey, err)
+			return
+		} else if !settings.AcceptingContracts {
+			log.Printf("Skipping host %v, not accepting contracts", c.PublicKey)
+			return
+		}
+		c.Settings = settings
+		c.Cost, _ = estimateContractCost(settings, usage, duration)
+		c.Score = scoreHost(c.Cost, c.Uptime, c.FirstSeen)
+		scanned[i] = true
+	})
+
+	ranked := candidates[:0]
+	for i, c := range candidates {
+		if scanned[i] {
+			ranked = append(ranked, c)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.sia.tech/renterd/slab"
+	"lukechampine.com/frand"
+)
+
+type (
+	// A journalFile is a file included in a journaled upload.
+	journalFile struct {
+		Path    string    `json:"path"`
+		Key     string    `json:"key"`
+		Size    int64     `json:"size"`
+		ModTime time.Time `json:"modTime"`
+	}
+
+	// A journalSlab is a slab that has been successfully uploaded. Offset is
+	// the slab's position in the packed upload stream.
+	journalSlab struct {
+		Index  int       `json:"index"`
+		Offset int64     `json:"offset"`
+		Slab   slab.Slab `json:"slab"`
+	}
+
+	// An uploadJournal records the progress of an upload in the data
+	// directory so an interrupted upload can be resumed without re-uploading
+	// the slabs that already completed.
+	uploadJournal struct {
+		ID          string        `json:"id"`
+		MinShards   uint8         `json:"minShards"`
+		TotalShards uint8         `json:"totalShards"`
+		Files       []journalFile `json:"files"`
+		Slabs       []journalSlab `json:"slabs"`
+
+		mu   sync.Mutex
+		path string
+	}
+)
+
+// uploadJournalDir returns the directory upload journals are stored in.
+func uploadJournalDir(dataDir string) string {
+	return filepath.Join(dataDir, "uploads")
+}
+
+// newUploadJournal creates a new journal for uploading files under the
+// corresponding object keys. The journal is not written to the data directory
+// until create is called.
+func newUploadJournal(dataDir string, minShards, totalShards uint8, files, keys []string) (*uploadJournal, error) {
+	id := hex.EncodeToString(frand.Bytes(8))
+	j := &uploadJournal{
+		ID:          id,
+		MinShards:   minShards,
+		TotalShards: totalShards,
+		path:        filepath.Join(uploadJournalDir(dataDir), id+".json"),
+	}
+
+	for i, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file %v: %w", file, err)
+		}
+		// store the absolute path so the upload can be resumed from any
+		// working directory
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path of %v: %w", file, err)
+		}
+		j.Files = append(j.Files, journalFile{
+			Path:    path,
+			Key:     keys[i],
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+		})
+	}
+
+	return j, nil
+}
+
+// loadUploadJournal loads an existing upload journal from the data directory.
+func loadUploadJournal(dataDir, id string) (*uploadJournal, error) {
+	path := filepath.Join(uploadJournalDir(dataDir), id+".json")
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload journal %v: %w", id, err)
+	}
+
+	j := &uploadJournal{path: path}
+	if err := json.Unmarshal(buf, j); err != nil {
+		return nil, fmt.Errorf("failed to decode upload journal %v: %w", id, err)
+	}
+	return j, nil
+}
+
+// create writes a new journal to the data directory.
+func (j *uploadJournal) create() error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	return j.save()
+}
+
+// save atomically writes the journal to disk. The caller must hold the lock
+// or have exclusive access to the journal.
+func (j *uploadJournal) save() error {
+	buf, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode upload journal: %w", err)
+	}
+
+	tmpPath := j.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create upload journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(buf); err != nil {
+		return fmt.Errorf("failed to write upload journal: %w", err)
+	} else if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync upload journal: %w", err)
+	} else if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close upload journal: %w", err)
+	} else if err := os.Rename(tmpPath, j.path); err != nil {
+		return fmt.Errorf("failed to replace upload journal: %w", err)
+	}
+	return nil
+}
+
+// totalSize returns the total number of bytes in the upload.
+func (j *uploadJournal) totalSize() (n int64) {
+	for _, f := range j.Files {
+		n += f.Size
+	}
+	return
+}
+
+// completed returns the slabs that have already been uploaded, keyed by their
+// index in the upload stream.
+func (j *uploadJournal) completed() map[int]slab.Slab {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	completed := make(map[int]slab.Slab, len(j.Slabs))
+	for _, s := range j.Slabs {
+		completed[s.Index] = s.Slab
+	}
+	return completed
+}
+
+// addSlab records a successfully uploaded slab and persists the journal.
+func (j *uploadJournal) addSlab(index int, offset int64, s slab.Slab) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Slabs = append(j.Slabs, journalSlab{
+		Index:  index,
+		Offset: offset,
+		Slab:   s,
+	})
+	return j.save()
+}
+
+// verifyFiles checks that none of the journaled files have been modified
+// since the upload started.
+func (j *uploadJournal) verifyFiles() error {
+	for _, f := range j.Files {
+		fi, err := os.Stat(f.Path)
+		if err != nil {
+			return fmt.Errorf("failed to stat file %v: %w", f.Path, err)
+		} else if fi.Size() != f.Size || !fi.ModTime().Equal(f.ModTime) {
+			return fmt.Errorf("file %v has changed since the upload started", f.Path)
+		}
+	}
+	return nil
+}
+
+// remove deletes the journal from the data directory.
+func (j *uploadJournal) remove() error {
+	return os.Remove(j.path)
+}
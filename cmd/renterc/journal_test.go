@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.sia.tech/renterd/slab"
+)
+
+func TestUploadJournal(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+
+	files := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	for i, f := range files {
+		if err := os.WriteFile(f, make([]byte, 100*(i+1)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	j, err := newUploadJournal(dataDir, 1, 3, files, []string{"dir/a", "dir/b"})
+	if err != nil {
+		t.Fatal(err)
+	} else if j.totalSize() != 300 {
+		t.Fatalf("expected total size 300, got %v", j.totalSize())
+	}
+
+	// the journal must not be written until it is created
+	if _, err := loadUploadJournal(dataDir, j.ID); err == nil {
+		t.Fatal("expected journal to not exist before create")
+	} else if err := j.create(); err != nil {
+		t.Fatal(err)
+	}
+
+	slabs := []slab.Slab{
+		{Key: slab.GenerateEncryptionKey(), MinShards: 1, Shards: make([]slab.Sector, 3)},
+		{Key: slab.GenerateEncryptionKey(), MinShards: 1, Shards: make([]slab.Sector, 3)},
+	}
+	if err := j.addSlab(1, 100, slabs[1]); err != nil {
+		t.Fatal(err)
+	} else if err := j.addSlab(0, 0, slabs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadUploadJournal(dataDir, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if loaded.ID != j.ID || loaded.MinShards != 1 || loaded.TotalShards != 3 {
+		t.Fatalf("loaded journal %v (%v-of-%v) does not match %v", loaded.ID, loaded.MinShards, loaded.TotalShards, j.ID)
+	} else if len(loaded.Files) != len(files) {
+		t.Fatalf("expected %v files, got %v", len(files), len(loaded.Files))
+	}
+	for i, f := range loaded.Files {
+		if f.Key != j.Files[i].Key || f.Path != j.Files[i].Path || f.Size != j.Files[i].Size || !f.ModTime.Equal(j.Files[i].ModTime) {
+			t.Fatalf("file %v: expected %+v, got %+v", i, j.Files[i], f)
+		}
+	}
+
+	completed := loaded.completed()
+	if len(completed) != len(slabs) {
+		t.Fatalf("expected %v completed slabs, got %v", len(slabs), len(completed))
+	}
+	for i, s := range slabs {
+		if !reflect.DeepEqual(completed[i], s) {
+			t.Fatalf("slab %v: expected %v, got %v", i, s, completed[i])
+		}
+	}
+
+	if err := loaded.remove(); err != nil {
+		t.Fatal(err)
+	} else if _, err := loadUploadJournal(dataDir, j.ID); err == nil {
+		t.Fatal("expected journal to be removed")
+	}
+}
+
+func TestUploadJournalVerifyFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(path string) error
+		valid  bool
+	}{
+		{"unchanged", func(string) error { return nil }, true},
+		{"modified", func(path string) error {
+			return os.Chtimes(path, time.Now(), time.Now().Add(time.Hour))
+		}, false},
+		{"resized", func(path string) error {
+			return os.WriteFile(path, make([]byte, 10), 0600)
+		}, false},
+		{"removed", os.Remove, false},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "file")
+		if err := os.WriteFile(path, make([]byte, 100), 0600); err != nil {
+			t.Fatal(err)
+		}
+		j, err := newUploadJournal(dir, 1, 1, []string{path}, []string{"file"})
+		if err != nil {
+			t.Fatal(err)
+		} else if err := test.modify(path); err != nil {
+			t.Fatal(err)
+		}
+
+		err = j.verifyFiles()
+		if test.valid && err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Fatalf("%v: expected verification to fail", test.name)
+		}
+	}
+}
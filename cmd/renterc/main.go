@@ -12,12 +12,9 @@ import (
 	"runtime"
 	"time"
 
-	"github.com/rodaine/table"
 	"github.com/siacentral/apisdkgo"
-	"github.com/siacentral/apisdkgo/sia"
 	"github.com/spf13/cobra"
 	"go.sia.tech/renterd/api"
-	"go.sia.tech/siad/types"
 	"lukechampine.com/frand"
 )
 
@@ -77,6 +74,7 @@ var (
 	dryRun      bool
 	skipConfirm bool
 	hashAlgo    string
+	parallel    int
 	renterPriv  api.PrivateKey
 )
 
@@ -87,37 +85,6 @@ var (
 		Run:   func(cmd *cobra.Command, args []string) {},
 	}
 
-	hostsCmd = &cobra.Command{
-		Use:   "hosts",
-		Short: "get a list of hosts",
-		Run: func(cmd *cobra.Command, args []string) {
-			// initialize the Sia Central API client
-			siaCentralClient := apisdkgo.NewSiaClient()
-
-			// get the list of hosts
-			acceptingContracts, benchmarked := true, true
-			maxContractPrice := types.SiacoinPrecision.Div64(2)
-			var minUptime float32 = 0.85
-			hosts, err := siaCentralClient.GetActiveHosts(sia.HostFilter{
-				AcceptingContracts: &acceptingContracts,
-				MaxContractPrice:   &maxContractPrice,
-				MinUptime:          &minUptime,
-				Benchmarked:        &benchmarked,
-			})
-			if err != nil {
-				log.Fatalln("failed to get hosts:", err)
-			}
-			tbl := table.New("#", "Public Key", "Storage Price", "Ingress Price", "Egress Price", "First Seen", "Est. Uptime")
-			for i, host := range hosts {
-				storagePrice := fmt.Sprintf("%v/TBmo", host.Settings.StoragePrice.Mul64(1e12).Mul64(4320).HumanString())
-				uploadPrice := fmt.Sprintf("%v/TB", host.Settings.UploadBandwidthPrice.Mul64(1e12).HumanString())
-				downloadPrice := fmt.Sprintf("%v/TB", host.Settings.DownloadBandwidthPrice.Mul64(1e12).HumanString())
-				tbl.AddRow(i+1, host.PublicKey, storagePrice, uploadPrice, downloadPrice, host.FirstSeenTimestamp.Local().Format(time.RFC822), fmt.Sprintf("%.2f%%", host.EstimatedUptime))
-			}
-			tbl.Print()
-		},
-	}
-
 	keyCmd = &cobra.Command{
 		Use:   "key",
 		Short: "get the renter's private key",
@@ -132,19 +99,86 @@ func init() {
 
 	// register contract flags
 	formCmd.Flags().StringVarP(&contractDurationStr, "duration", "D", "1w", "contract duration, accepts a duration and suffix (e.g. 1w)")
-	formCmd.Flags().StringVarP(&contractUsageStr, "usage", "U", "1GiB", "contract usage, accepts a size and suffix (e.g. 1TiB)")
+	formCmd.Flags().StringVar(&contractStorageStr, "storage", "1GiB", "expected data stored, accepts a size and suffix (e.g. 1TiB)")
+	formCmd.Flags().StringVar(&contractUploadStr, "upload", "1GiB", "expected data uploaded, accepts a size and suffix (e.g. 1TiB)")
+	formCmd.Flags().StringVar(&contractDownloadStr, "download", "1GiB", "expected data downloaded, accepts a size and suffix (e.g. 1TiB)")
+	formCmd.Flags().IntVarP(&formParallel, "parallel", "p", 4, "number of contracts to form concurrently")
+	formCmd.Flags().StringVar(&maxCostStr, "max-cost", "", "maximum cost of each contract including the miner fee (e.g. 10SC)")
+	formCmd.Flags().StringVar(&hostAddrFlag, "addr", "", "host net address, skips the host source lookup")
+	formCmd.Flags().IntVar(&autoHosts, "auto", 0, "form contracts with the n best hosts")
+	formCmd.Flags().BoolVarP(&skipConfirm, "confirm", "y", false, "skip confirmation prompt")
+	formCmd.Flags().StringVar(&formBudgetStr, "budget", "", "maximum total cost of contracts formed with --auto including miner fees (e.g. 50SC)")
+
+	quoteCmd.Flags().StringVarP(&contractDurationStr, "duration", "D", "1w", "contract duration, accepts a duration and suffix (e.g. 1w)")
+	quoteCmd.Flags().StringVar(&contractStorageStr, "storage", "1GiB", "expected data stored, accepts a size and suffix (e.g. 1TiB)")
+	quoteCmd.Flags().StringVar(&contractUploadStr, "upload", "1GiB", "expected data uploaded, accepts a size and suffix (e.g. 1TiB)")
+	quoteCmd.Flags().StringVar(&contractDownloadStr, "download", "1GiB", "expected data downloaded, accepts a size and suffix (e.g. 1TiB)")
+
+	renewCmd.Flags().StringVarP(&contractDurationStr, "duration", "D", "1w", "renewed contract duration from the current height, accepts a duration and suffix (e.g. 1w)")
+	renewCmd.Flags().StringVarP(&contractUsageStr, "usage", "U", "1GiB", "additional contract usage, accepts a size and suffix (e.g. 1TiB)")
+	renewCmd.Flags().StringVar(&expiringWithinStr, "expiring-within", "", "renew every contract expiring within the duration (e.g. 1w)")
+
+	refillCmd.Flags().StringVar(&refillAmountStr, "amount", "", "amount to add to each contract (e.g. 10SC)")
+	refillCmd.Flags().StringVar(&refillThresholdStr, "threshold", "", "refill every contract with less than the amount remaining (e.g. 1SC)")
+
+	// register host flags
+	hostsCmd.PersistentFlags().StringVar(&hostMaxContractPrice, "max-contract-price", "0.5SC", "maximum contract price")
+	hostsCmd.PersistentFlags().StringVar(&hostMaxStoragePrice, "max-storage-price", "", "maximum storage price per TB per month")
+	hostsCmd.PersistentFlags().StringVar(&hostMaxIngressPrice, "max-ingress-price", "", "maximum upload price per TB")
+	hostsCmd.PersistentFlags().StringVar(&hostMaxEgressPrice, "max-egress-price", "", "maximum download price per TB")
+	hostsCmd.PersistentFlags().Float64Var(&hostMinUptime, "min-uptime", 0.85, "minimum estimated uptime")
+	hostsCmd.PersistentFlags().StringVar(&hostMinAge, "min-age", "", "minimum time since the host was first seen, accepts a duration and suffix (e.g. 1m)")
+	hostsCmd.PersistentFlags().BoolVar(&hostAccepting, "accepting", true, "only list hosts accepting contracts")
+	hostsCmd.PersistentFlags().BoolVar(&hostBenchmarked, "benchmarked", true, "only list hosts that have been benchmarked")
+	hostsCmd.Flags().StringSliceVar(&hostCountries, "country", nil, "only list hosts in the countries (e.g. US,DE)")
+	hostsCmd.Flags().StringVar(&hostSortBy, "sort", "", "column to sort by: key, contract, storage, ingress, egress, age, uptime or country")
+	hostsCmd.Flags().BoolVar(&hostSortDesc, "desc", false, "sort in descending order")
+	hostsCmd.Flags().IntVar(&hostLimit, "limit", 0, "maximum number of hosts to list, 0 lists every host")
+
+	verifyCmd.Flags().BoolVar(&fixRevisions, "fix", false, "update renterd with the host's revision when it is newer")
+
+	// register autopilot flags
+	autopilotCmd.Flags().DurationVar(&autopilotInterval, "interval", 10*time.Minute, "time between contract checks")
+	autopilotCmd.Flags().IntVar(&autopilotHosts, "hosts", 3, "target number of hosts with active contracts")
+	autopilotCmd.Flags().StringVar(&autopilotAllowance, "allowance", "100SC", "maximum amount to spend on contracts per period")
+	autopilotCmd.Flags().StringVar(&autopilotRenewWindow, "renew-window", "1w", "renew contracts whose proof window starts within the duration (e.g. 1w)")
+	autopilotCmd.Flags().StringVarP(&autopilotDurationStr, "duration", "D", "1m", "contract duration and allowance period, accepts a duration and suffix (e.g. 1m)")
+	autopilotCmd.Flags().StringVarP(&contractUsageStr, "usage", "U", "1GiB", "contract usage, accepts a size and suffix (e.g. 1TiB)")
 
 	// register file flags
 	downloadCmd.Flags().BoolVarP(&skipConfirm, "confirm", "y", false, "skip confirmation prompt")
 	downloadCmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run, don't actually download the file")
 	downloadCmd.Flags().StringVarP(&hashAlgo, "algo", "a", "sha256", "hash algorithm to use for verification")
+	downloadCmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "number of slabs to download concurrently")
+	downloadCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "download every object under a prefix")
+	downloadCmd.Flags().BoolVar(&resumeDownload, "resume", false, "resume a partial download from the last complete slab")
+	downloadCmd.Flags().Int64Var(&downloadOffset, "offset", 0, "byte offset in the object to start downloading from")
+	downloadCmd.Flags().Int64Var(&downloadLength, "length", 0, "number of bytes to download, 0 downloads to the end of the object")
+
+	deleteCmd.Flags().BoolVar(&pruneSlabs, "prune", false, "delete the sectors of slabs that are no longer referenced")
+	deleteCmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run, report the slab references without deleting")
+
+	repairCmd.Flags().BoolVar(&repairAll, "all", false, "repair every object")
+	repairCmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run, report the slabs that need repair without repairing them")
 
 	uploadCmd.Flags().Uint8VarP(&minShards, "min-shards", "m", 1, "minimum number of shards")
 	uploadCmd.Flags().Uint8VarP(&totalShards, "total-shards", "n", 1, "total number of shards")
 	uploadCmd.Flags().StringVarP(&hashAlgo, "algo", "a", "sha256", "hash algorithm to use for verification")
+	uploadCmd.Flags().StringVar(&profileName, "profile", "", "named redundancy profile to use instead of -m and -n")
+	uploadCmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "number of slabs to upload concurrently")
+	uploadCmd.Flags().StringVar(&resumeID, "resume", "", "resume an interrupted upload by its journal id")
+	uploadCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "upload directories recursively")
+	uploadCmd.Flags().StringVar(&objectPrefix, "prefix", "", "prefix to add to every object key")
 
 	// wallet flags
 	fragCmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run, don't actually broadcast the transaction")
+	fragCmd.Flags().StringVar(&minerFeeStr, "fee", "", "total miner fee, overrides renterd's recommended fee (e.g. 0.1SC)")
+	fragCmd.Flags().StringVar(&feePerByteStr, "fee-per-byte", "", "miner fee per byte of the transaction, overrides renterd's recommended rate (e.g. 10uS)")
+
+	sendCmd.Flags().BoolVar(&dryRun, "dry-run", false, "dry run, don't actually broadcast the transaction")
+	sendCmd.Flags().BoolVarP(&skipConfirm, "confirm", "y", false, "skip confirmation prompt")
+	sendCmd.Flags().StringVar(&minerFeeStr, "fee", "", "total miner fee, overrides renterd's recommended fee (e.g. 0.1SC)")
+	sendCmd.Flags().StringVar(&feePerByteStr, "fee-per-byte", "", "miner fee per byte of the transaction, overrides renterd's recommended rate (e.g. 10uS)")
 
 	// register global flags
 	defaultDataDir := "."
@@ -157,6 +191,7 @@ func init() {
 		defaultDataDir = filepath.Join(os.Getenv("HOME"), ".local/renterc")
 	}
 	rootCmd.PersistentFlags().StringVarP(&dataDir, "dir", "d", defaultDataDir, "data directory")
+	rootCmd.PersistentFlags().StringVar(&hostSource, "host-source", hostSourceAuto, "where to look up hosts: auto, renterd or siacentral")
 
 	// before running any command, load the renter key and initialize the
 	// directory
@@ -173,13 +208,16 @@ func init() {
 	}
 
 	// add contract commands
-	contractsCmd.AddCommand(formCmd)
+	contractsCmd.AddCommand(formCmd, quoteCmd, renewCmd, refillCmd, reportCmd, archivedCmd, verifyCmd)
 	// add file commands
-	objectsCmd.AddCommand(uploadCmd, downloadCmd)
+	profilesCmd.AddCommand(setProfileCmd)
+	objectsCmd.AddCommand(uploadCmd, downloadCmd, deleteCmd, repairCmd, healthCmd, profilesCmd)
+	// add host commands
+	hostsCmd.AddCommand(scanHostsCmd)
 	// add wallet commands
-	walletCmd.AddCommand(addressCmd, balanceCmd, fragCmd)
+	walletCmd.AddCommand(addressCmd, balanceCmd, fragCmd, sendCmd)
 	// add commands to root
-	rootCmd.AddCommand(keyCmd, contractsCmd, hostsCmd, objectsCmd, walletCmd)
+	rootCmd.AddCommand(keyCmd, contractsCmd, hostsCmd, objectsCmd, walletCmd, autopilotCmd)
 }
 
 func main() {
@@ -2,16 +2,15 @@ package main
 
 import (
 	"bufio"
-	"crypto/md5"
-	"crypto/sha1"
-	"crypto/sha256"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"hash"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -23,13 +22,26 @@ import (
 	"go.sia.tech/renterd/object"
 	"go.sia.tech/renterd/rhp/v2"
 	"go.sia.tech/renterd/slab"
+	"go.sia.tech/siad/types"
 	"lukechampine.com/frand"
 )
 
 var (
 	// upload command args
-	minShards   uint8
-	totalShards uint8
+	minShards    uint8
+	totalShards  uint8
+	resumeID     string
+	recursive    bool
+	objectPrefix string
+	profileName  string
+
+	// download command args
+	resumeDownload bool
+	downloadOffset int64
+	downloadLength int64
+
+	// delete command args
+	pruneSlabs bool
 )
 
 var (
@@ -70,11 +82,61 @@ var (
 
 Splits the local file(s) into shards and uploads them to the Sia network. The files will be packed together if multiple paths are specified to reduce wasted storage space.
 
-The flags -m and -n are used to control redundancy. m is the minimum number of shards required to recover the file, and n is the total number of hosts to use. A file with -m 1 -n 3 would be uploaded to 3 hosts, with 1 host required to recover the file. The siad renter defaults to -m 10 -n 30 for 3x redundancy across 30 hosts. The default is -m 1 -n 1, which has no redundancy. You must form contracts with at least <n> hosts before uploading.`,
-		Run: func(cmd *cobra.Command, files []string) {
+Objects are keyed by the file's name. With -r, directories are walked recursively and every file is keyed by its path relative to the directory. The whole tree is packed together. --prefix is prepended to every key.
+
+The flags -m and -n are used to control redundancy. m is the minimum number of shards required to recover the file, and n is the total number of hosts to use. A file with -m 1 -n 3 would be uploaded to 3 hosts, with 1 host required to recover the file. The siad renter defaults to -m 10 -n 30 for 3x redundancy across 30 hosts. The default is -m 1 -n 1, which has no redundancy. You must form contracts with at least <n> hosts before uploading.
+
+Instead of -m and -n, --profile selects a named redundancy profile. The profiles none (1-of-1), mirror (1-of-3) and archive (10-of-30) are built in, more can be added with renterc objects profiles set. The expansion factor and estimated upload cost are printed before the upload starts.
+
+The flag -p controls how many slabs are uploaded concurrently. Each in-flight slab is buffered in memory, so memory usage grows with m * 4MiB per parallel upload.
+
+Progress is journaled in the data directory. If an upload is interrupted, run renterc objects upload --resume <id> to finish it without re-uploading completed slabs. The erasure coding settings from the original upload are reused.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if resumeID != "" && len(args) != 0 {
+				return errors.New("file arguments are not allowed when using --resume")
+			} else if resumeID == "" && len(args) == 0 {
+				return errors.New("at least one file is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, paths []string) {
+			if resumeID != "" {
+				start := time.Now()
+				if err := resumeUpload(renterPriv, resumeID); err != nil {
+					log.Fatalln("failed to resume upload:", err)
+				}
+				log.Printf("Resumed upload %v completed in %v", resumeID, time.Since(start))
+				return
+			}
+
+			// validate the redundancy before reading any files
+			profile := redundancyProfile{MinShards: minShards, TotalShards: totalShards}
+			if profileName != "" {
+				if cmd.Flags().Changed("min-shards") || cmd.Flags().Changed("total-shards") {
+					log.Fatalln("-m and -n cannot be used with --profile")
+				}
+				profiles, err := loadProfiles(dataDir)
+				if err != nil {
+					log.Fatalln("failed to load profiles:", err)
+				}
+				p, ok := profiles[profileName]
+				if !ok {
+					log.Fatalf("unknown profile %q", profileName)
+				}
+				profile = p
+			}
+			if err := profile.validate(); err != nil {
+				log.Fatalln("invalid redundancy:", err)
+			}
+
+			files, keys, err := collectUploadFiles(paths, recursive, objectPrefix)
+			if err != nil {
+				log.Fatalln("failed to collect files:", err)
+			}
+
 			log.Printf("Uploading %v objects", len(files))
 			start := time.Now()
-			if err := uploadFiles(renterPriv, minShards, totalShards, files); err != nil {
+			if err := uploadFiles(renterPriv, profile.MinShards, profile.TotalShards, files, keys); err != nil {
 				log.Fatalln("failed to upload file:", err)
 			}
 			log.Printf("Uploaded %v objects in %v", len(files), time.Since(start))
@@ -84,7 +146,12 @@ The flags -m and -n are used to control redundancy. m is the minimum number of s
 	downloadCmd = &cobra.Command{
 		Use:   "download",
 		Short: "download a file from the network",
-		Long:  "renterc download <object> <file>",
+		Long: `renterc download [flags] <object> <file>
+renterc download -r [flags] <prefix> <dir>
+
+Downloads an object from the network. With -r, every object under the prefix is downloaded into the directory, recreating the object hierarchy locally. Use --offset and --length to download only a byte range of the object; only the slabs covering the range are fetched. Use --resume to continue a partial download from the last complete slab instead of starting over.
+
+The flag -p controls how many slabs are downloaded concurrently. Slabs are reassembled in order, so each in-flight slab is buffered in memory until the slabs before it have been written.`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if dryRun && len(args) != 1 {
 				return errors.New("only the object key arg is allowed when using --dry-run")
@@ -93,33 +160,61 @@ The flags -m and -n are used to control redundancy. m is the minimum number of s
 			}
 			return nil
 		},
-		Run: func(cmd *cobra.Command, files []string) {
+		Run: func(cmd *cobra.Command, args []string) {
 			var outputPath string
-			key := files[0]
+			key := args[0]
 			if !dryRun {
-				outputPath = files[1]
+				outputPath = args[1]
+			}
+
+			dc, err := newDownloadContracts(renterPriv)
+			if err != nil {
+				log.Fatalln("failed to get contracts:", err)
 			}
 
-			if !skipConfirm {
-				if _, err := os.Stat(outputPath); err == nil {
-					fmt.Printf("file %v already exists. Overwrite? (y/n): ", outputPath)
-					var confirm string
-					fmt.Scanln(&confirm)
-					if s := strings.ToLower(confirm); s != "y" && s != "yes" {
-						log.Fatalln("download aborted")
-					}
+			if recursive {
+				start := time.Now()
+				if err := downloadPrefix(dc, key, outputPath); err != nil {
+					log.Fatalln("failed to download objects:", err)
 				}
+				log.Printf("Downloaded %v in %v", key, time.Since(start))
+				return
+			}
+
+			if !skipConfirm && !resumeDownload && !confirmOverwrite(outputPath) {
+				log.Fatalln("download aborted")
 			}
 
 			println("Downloading object with key", key)
 			start := time.Now()
-			checksum, err := downloadFile(renterPriv, key, outputPath)
+			checksum, err := downloadFile(dc, key, outputPath, downloadOffset, downloadLength, resumeDownload)
 			if err != nil {
 				log.Fatalln("failed to download file:", err)
 			}
 			log.Printf("Downloaded %v in %v (%v %x)", key, time.Since(start), hashAlgo, checksum)
 		},
 	}
+
+	deleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete an object",
+		Long: `renterc objects delete [flags] <key>
+
+Removes the object from renterd and reports which of its slabs are no longer referenced by any object and which are still shared with other objects that were packed with it.
+
+With --prune, the sectors of unreferenced slabs are deleted from their hosts. Sectors on hosts without a usable contract cannot be pruned and will expire with the contract.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("exactly one object key is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := deleteObject(renterPriv, args[0], pruneSlabs); err != nil {
+				log.Fatalln("failed to delete object:", err)
+			}
+		},
+	}
 )
 
 // getUsableContracts returns a list of contracts that can be used for storage
@@ -140,10 +235,12 @@ func getUsableContracts(renterPriv api.PrivateKey, required int) ([]api.Contract
 	// remove contracts that are expired or empty
 	usable := make([]api.Contract, 0, len(contracts))
 	for _, contract := range contracts {
-		// if the contract has expired, remove it. If it's is too close to the
+		// if the contract has expired, archive it. If it's is too close to the
 		// proof window start or if no renter funds remain, skip it
 		if tip.Height > contract.EndHeight() {
-			renterdClient.DeleteContract(contract.ID())
+			if err := archiveContract(dataDir, contract, tip.Height, archiveReasonExpired); err != nil {
+				log.Printf("failed to archive expired contract %v: %v", contract.ID(), err)
+			}
 			continue
 		} else if tip.Height >= uint64(contract.Revision.NewWindowStart)-144 || contract.Revision.NewValidProofOutputs[0].Value.IsZero() {
 			continue
@@ -172,59 +269,155 @@ func getUsableContracts(renterPriv api.PrivateKey, required int) ([]api.Contract
 	return usable, nil
 }
 
+// collectUploadFiles returns the files to upload and their object keys. Files
+// are keyed by their base name. If recursive is true, directories are walked
+// and their files are keyed by their slash-separated path relative to the
+// directory. prefix is prepended to every key.
+func collectUploadFiles(paths []string, recursive bool, prefix string) (files, keys []string, err error) {
+	seen := make(map[string]string)
+	add := func(file, key string) error {
+		key = path.Join(prefix, key)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("files %v and %v would both be uploaded as %v", other, file, key)
+		}
+		seen[key] = file
+		files = append(files, file)
+		keys = append(keys, key)
+		return nil
+	}
+
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to stat file %v: %w", p, err)
+		} else if !fi.IsDir() {
+			if err := add(p, filepath.Base(p)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		} else if !recursive {
+			return nil, nil, fmt.Errorf("%v is a directory, use -r to upload directories", p)
+		}
+
+		err = filepath.WalkDir(p, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			} else if !d.Type().IsRegular() {
+				// skip directories, symlinks and other special files
+				return nil
+			}
+
+			rel, err := filepath.Rel(p, file)
+			if err != nil {
+				return err
+			}
+			return add(file, filepath.ToSlash(rel))
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to walk directory %v: %w", p, err)
+		}
+	}
+	return files, keys, nil
+}
+
 // uploadFiles uploads files to the Sia network and adds a new object for each
-// file to renterd
-func uploadFiles(renterPriv api.PrivateKey, minShards, totalShards uint8, files []string) error {
-	for _, f := range files {
-		if _, err := os.Stat(f); err != nil {
-			return fmt.Errorf("failed to stat file %v: %w", f, err)
+// file to renterd under the corresponding key. Progress is recorded in an
+// upload journal so the upload can be resumed with resumeUpload if it is
+// interrupted. The journal is only created once the upload's contracts have
+// been chosen.
+func uploadFiles(renterPriv api.PrivateKey, minShards, totalShards uint8, files, keys []string) error {
+	j, err := newUploadJournal(dataDir, minShards, totalShards, files, keys)
+	if err != nil {
+		return err
+	}
+	contracts, err := uploadContracts(renterPriv, j)
+	if err != nil {
+		return err
+	} else if err := j.create(); err != nil {
+		return fmt.Errorf("failed to create upload journal: %w", err)
+	}
+	log.Printf("Upload %v started, resume with --resume %v if interrupted", j.ID, j.ID)
+	return uploadJournaled(j, contracts)
+}
+
+// resumeUpload resumes an interrupted upload, skipping the slabs that were
+// already uploaded.
+func resumeUpload(renterPriv api.PrivateKey, id string) error {
+	j, err := loadUploadJournal(dataDir, id)
+	if err != nil {
+		return err
+	} else if err := j.verifyFiles(); err != nil {
+		return fmt.Errorf("cannot resume upload: %w", err)
+	}
+	contracts, err := uploadContracts(renterPriv, j)
+	if err != nil {
+		return err
+	}
+	log.Printf("Resuming upload %v, %v slabs already uploaded", j.ID, len(j.Slabs))
+	return uploadJournaled(j, contracts)
+}
+
+// uploadContracts chooses the contracts to upload the journal's remaining
+// slabs to and logs the estimated cost of uploading them.
+func uploadContracts(renterPriv api.PrivateKey, j *uploadJournal) ([]api.Contract, error) {
+	minShards, totalShards := j.MinShards, j.TotalShards
+	for _, f := range j.Files {
+		if _, err := os.Stat(f.Path); err != nil {
+			return nil, fmt.Errorf("failed to stat file %v: %w", f.Path, err)
 		}
 	}
 
 	// choose the contracts to use
 	contracts, err := getUsableContracts(renterPriv, int(totalShards))
 	if err != nil {
-		return fmt.Errorf("failed to get usable contracts: %w", err)
+		return nil, fmt.Errorf("failed to get usable contracts: %w", err)
+	}
+
+	// estimate the cost of the remaining slabs
+	profile := redundancyProfile{MinShards: minShards, TotalShards: totalShards}
+	slabSize := int64(minShards) * rhp.SectorSize
+	remainingSlabs := (j.totalSize()+slabSize-1)/slabSize - int64(len(j.Slabs))
+	log.Printf("Using %v redundancy, %.2fx expansion", profile, profile.expansion())
+	if cost, err := estimateUploadCost(contracts[:totalShards], uint64(remainingSlabs)); err != nil {
+		log.Println("failed to estimate upload cost:", err)
+	} else {
+		log.Printf("Estimated upload cost: %v", cost.HumanString())
 	}
+	return contracts, nil
+}
+
+// uploadJournaled uploads the files in the journal to the contracts, skipping
+// any slabs that have already been uploaded, and removes the journal once
+// every object has been added to renterd.
+func uploadJournaled(j *uploadJournal, contracts []api.Contract) error {
+	minShards, totalShards := j.MinShards, j.TotalShards
 
 	// create the hasher
-	var h hash.Hash
-	switch strings.ToLower(hashAlgo) {
-	case "sha256":
-		h = sha256.New()
-	case "sha1":
-		h = sha1.New()
-	case "md5":
-		h = md5.New()
-	default:
-		return fmt.Errorf("unknown hash algorithm: %v", hashAlgo)
+	h, err := newHasher(hashAlgo)
+	if err != nil {
+		return err
 	}
 
 	// get the total upload length
-	var totalUploadBytes int64
-	for _, file := range files {
-		fi, err := os.Stat(file)
-		if err != nil {
-			return fmt.Errorf("failed to stat file %v: %w", file, err)
-		}
-		totalUploadBytes += fi.Size()
-	}
+	totalUploadBytes := j.totalSize()
 
-	lengths := make([]int, 0, len(files))
-	checksums := make([][]byte, 0, len(files))
+	lengths := make([]int, 0, len(j.Files))
+	checksums := make([][]byte, 0, len(j.Files))
 
 	// use io.Pipe to treat all files as a continuous stream and pack them
 	// together
 	r, w := io.Pipe()
+	copyErr := make(chan error, 1)
 	go func() {
-		var wg sync.WaitGroup
-		wg.Add(len(files))
+		defer close(copyErr)
 
-		for _, file := range files {
+		// every file is read, even on resume, so the lengths and checksums
+		// cover the whole file
+		for _, file := range j.Files {
 			h.Reset()
 
 			err := func() error {
-				f, err := os.Open(file)
+				f, err := os.Open(file.Path)
 				if err != nil {
 					return fmt.Errorf("failed to open file: %w", err)
 				}
@@ -242,46 +435,43 @@ func uploadFiles(renterPriv api.PrivateKey, minShards, totalShards uint8, files
 				return nil
 			}()
 			if err != nil {
-				panic(err)
+				// unblock the reader and report the error
+				w.CloseWithError(err)
+				copyErr <- err
+				return
 			}
-			wg.Done()
 		}
-
-		// wait for all files to be copied
-		wg.Wait()
 		w.Close()
 	}()
 
 	// grab the current block height
 	tip, err := renterdClient.ConsensusTip()
 	if err != nil {
+		r.CloseWithError(err)
 		return fmt.Errorf("failed to get consensus tip: %w", err)
 	}
 
 	// upload each slab, using the pipe as the source. Each file will be copied
 	// to the pipe, then the pipe will be closed.
-	var slabs []slab.Slab
-	maxSlabSize := int64(minShards) * rhp.SectorSize
-	// TODO: parallelize
-	for i, rem := 0, totalUploadBytes; rem > 0; i, rem = i+1, rem-maxSlabSize {
-		uploadSize := rem
-		if uploadSize > maxSlabSize {
-			// if the upload size is greater than the slab size, use the slab
-			// size
-			uploadSize = maxSlabSize
-		}
-		lr := io.LimitReader(r, uploadSize)
-		slab, err := renterdClient.UploadSlab(lr, minShards, totalShards, tip.Height, contracts)
-		if err != nil {
-			return fmt.Errorf("failed to upload slab %v: %w", i, err)
-		}
-		slabs = append(slabs, slab)
+	slabs, err := uploadSlabs(r, totalUploadBytes, minShards, totalShards, tip.Height, contracts, parallel, j)
+	if err != nil {
+		// stop the packing goroutine
+		r.CloseWithError(err)
+		return err
+	}
+
+	// close the reader so the packing goroutine fails if the files grew
+	// after they were stat'd, then wait for it to finish so the lengths and
+	// checksums are complete
+	r.Close()
+	if err := <-copyErr; err != nil {
+		return fmt.Errorf("failed to pack files: %w", err)
 	}
 
 	// split the uploaded slabs into objects and add each object to renterd
 	objs := object.SplitSlabs(slabs, lengths)
-	for i, file := range files {
-		key := filepath.Base(file)
+	for i, file := range j.Files {
+		key := file.Key
 		err = renterdClient.AddObject(key, object.Object{
 			Key:   object.GenerateEncryptionKey(),
 			Slabs: objs[i],
@@ -291,15 +481,269 @@ func uploadFiles(renterPriv api.PrivateKey, minShards, totalShards uint8, files
 			return fmt.Errorf("failed to add object %v: %w", key, err)
 		}
 	}
+
+	// the upload is complete, the journal is no longer needed
+	if err := j.remove(); err != nil {
+		log.Printf("failed to remove upload journal %v: %v", j.ID, err)
+	}
 	return nil
 }
 
-func downloadFile(renterPriv api.PrivateKey, objectKey, outputPath string) ([]byte, error) {
-	obj, err := renterdClient.Object(objectKey)
+// estimateUploadCost estimates the cost of uploading slabs to the contracts.
+// Each slab stores one sector on each contract's host and storage is paid
+// until the contract's end height. The hosts are scanned concurrently.
+func estimateUploadCost(contracts []api.Contract, slabs uint64) (types.Currency, error) {
+	tip, err := renterdClient.ConsensusTip()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object: %w", err)
+		return types.ZeroCurrency, fmt.Errorf("failed to get consensus tip: %w", err)
+	}
+
+	costs := make([]types.Currency, len(contracts))
+	errs := make([]error, len(contracts))
+	forEachConcurrent(len(contracts), maxConcurrentScans, func(i int) {
+		c := contracts[i]
+		contract, err := renterdClient.Contract(c.ID)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to get contract %v: %w", c.ID, err)
+			return
+		}
+		settings, err := renterdClient.RHPScan(c.HostKey, c.HostIP)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to scan host %v: %w", c.HostKey, err)
+			return
+		}
+
+		var duration uint64
+		if end := contract.EndHeight(); end > tip.Height {
+			duration = end - tip.Height
+		}
+		uploadCost := settings.UploadBandwidthPrice.Mul64(rhp.SectorSize)
+		storageCost := settings.StoragePrice.Mul64(rhp.SectorSize).Mul64(duration)
+		costs[i] = uploadCost.Add(storageCost).Mul64(slabs)
+	})
+
+	cost := types.ZeroCurrency
+	for i := range contracts {
+		if errs[i] != nil {
+			return types.ZeroCurrency, errs[i]
+		}
+		cost = cost.Add(costs[i])
+	}
+	return cost, nil
+}
+
+// uploadSlabs reads slabs from r and uploads them to the network with up to
+// parallel uploads in flight. Each slab is buffered in memory so the next
+// slab can be read while the previous ones upload. The returned slabs are in
+// the order they were read from r. Slabs already recorded in the journal are
+// read and discarded instead of being uploaded again, and each newly uploaded
+// slab is added to the journal. If any upload fails, no new uploads are
+// started and the first error is returned after the in-flight uploads finish.
+func uploadSlabs(r io.Reader, size int64, minShards, totalShards uint8, height uint64, contracts []api.Contract, parallel int, j *uploadJournal) ([]slab.Slab, error) {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	maxSlabSize := int64(minShards) * rhp.SectorSize
+	slabs := make([]slab.Slab, (size+maxSlabSize-1)/maxSlabSize)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		uploadErr error
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if uploadErr == nil {
+			uploadErr = err
+		}
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return uploadErr != nil
+	}
+
+	completed := j.completed()
+	sem := make(chan struct{}, parallel)
+	for i, rem := 0, size; rem > 0; i, rem = i+1, rem-maxSlabSize {
+		uploadSize := rem
+		if uploadSize > maxSlabSize {
+			// if the upload size is greater than the slab size, use the slab
+			// size
+			uploadSize = maxSlabSize
+		}
+
+		// skip slabs that were uploaded before the upload was interrupted
+		if s, ok := completed[i]; ok {
+			if _, err := io.CopyN(io.Discard, r, uploadSize); err != nil {
+				setErr(fmt.Errorf("failed to skip slab %v: %w", i, err))
+				break
+			}
+			slabs[i] = s
+			continue
+		}
+
+		// wait for an upload slot before reading the next slab to bound
+		// memory usage
+		sem <- struct{}{}
+		if failed() {
+			<-sem
+			break
+		}
+
+		buf := make([]byte, uploadSize)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			<-sem
+			setErr(fmt.Errorf("failed to read slab %v: %w", i, err))
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, offset int64, buf []byte) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			s, err := renterdClient.UploadSlab(bytes.NewReader(buf), minShards, totalShards, height, contracts)
+			if err != nil {
+				setErr(fmt.Errorf("failed to upload slab %v: %w", i, err))
+				return
+			}
+			slabs[i] = s
+
+			if err := j.addSlab(i, offset, s); err != nil {
+				setErr(fmt.Errorf("failed to journal slab %v: %w", i, err))
+			}
+		}(i, size-rem, buf)
+	}
+	wg.Wait()
+
+	if uploadErr != nil {
+		return nil, uploadErr
+	}
+	return slabs, nil
+}
+
+// sliceRange returns the slab slices covering length bytes of an object
+// starting at offset. The first and last slices are trimmed so only the
+// requested range is downloaded. A length of 0 covers the rest of the object.
+func sliceRange(slabs []slab.Slice, offset, length int64) ([]slab.Slice, error) {
+	var size int64
+	for _, s := range slabs {
+		size += int64(s.Length)
+	}
+
+	if offset < 0 || length < 0 {
+		return nil, errors.New("offset and length must not be negative")
+	} else if offset > size {
+		return nil, fmt.Errorf("offset %v is past the end of the object (%v bytes)", offset, size)
+	} else if length == 0 || offset+length > size {
+		length = size - offset
+	}
+
+	var slices []slab.Slice
+	for _, s := range slabs {
+		if length == 0 {
+			break
+		} else if offset >= int64(s.Length) {
+			// the range starts after this slice
+			offset -= int64(s.Length)
+			continue
+		}
+
+		n := int64(s.Length) - offset
+		if n > length {
+			n = length
+		}
+		s.Offset += uint32(offset)
+		s.Length = uint32(n)
+		slices = append(slices, s)
+		offset, length = 0, length-n
+	}
+	return slices, nil
+}
+
+// resumePoint returns the number of complete slices contained in the first
+// size bytes of a partial download and the byte offset where they end.
+func resumePoint(slices []slab.Slice, size int64) (n int, offset int64) {
+	for _, s := range slices {
+		if offset+int64(s.Length) > size {
+			break
+		}
+		offset += int64(s.Length)
+		n++
+	}
+	return
+}
+
+// downloadSlices downloads slices with up to parallel downloads in flight and
+// writes them to w in order. Each slice is buffered in memory until every
+// slice before it has been written, so w always receives the data in order
+// and any hash computed from it is correct. No new downloads are started after
+// the first failure.
+func downloadSlices(w io.Writer, slices []slab.Slice, contracts []api.Contract, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	type result struct {
+		buf *bytes.Buffer
+		err error
 	}
+	results := make([]chan result, len(slices))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	// a slot is released once its slice has been written, which bounds the
+	// number of buffered slices to parallel
+	sem := make(chan struct{}, parallel)
+	done := make(chan struct{})
+	defer close(done)
 
+	go func() {
+		for i, s := range slices {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			go func(i int, s slab.Slice) {
+				buf := bytes.NewBuffer(make([]byte, 0, s.Length))
+				err := renterdClient.DownloadSlab(buf, s, contracts)
+				results[i] <- result{buf, err}
+			}(i, s)
+		}
+	}()
+
+	for i := range slices {
+		res := <-results[i]
+		if res.err != nil {
+			return fmt.Errorf("failed to download slab %v: %w", i, res.err)
+		} else if _, err := res.buf.WriteTo(w); err != nil {
+			return fmt.Errorf("failed to write slab %v: %w", i, err)
+		}
+		<-sem
+	}
+	return nil
+}
+
+// downloadContracts caches the usable contracts and host addresses needed to
+// download objects so they are only looked up once when downloading many
+// objects.
+type downloadContracts struct {
+	renterPriv    api.PrivateKey
+	hostContracts map[api.PublicKey]rhp.Contract
+	resolved      map[api.PublicKey]api.Contract
+}
+
+// newDownloadContracts returns a downloadContracts containing every contract
+// that can currently be used for downloading.
+func newDownloadContracts(renterPriv api.PrivateKey) (*downloadContracts, error) {
 	currentContracts, err := renterdClient.Contracts()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get contracts: %w", err)
@@ -312,10 +756,12 @@ func downloadFile(renterPriv api.PrivateKey, objectKey, outputPath string) ([]by
 
 	hostContracts := make(map[api.PublicKey]rhp.Contract)
 	for _, c := range currentContracts {
-		// if the contract has expired, remove it. If it's is too close to the
+		// if the contract has expired, archive it. If it's is too close to the
 		// proof window start or if no renter funds remain, skip it
 		if tip.Height > c.EndHeight() {
-			renterdClient.DeleteContract(c.ID())
+			if err := archiveContract(dataDir, c, tip.Height, archiveReasonExpired); err != nil {
+				log.Printf("failed to archive expired contract %v: %v", c.ID(), err)
+			}
 			continue
 		} else if tip.Height >= uint64(c.Revision.NewWindowStart)-144 || c.Revision.NewValidProofOutputs[0].Value.IsZero() {
 			continue
@@ -324,32 +770,59 @@ func downloadFile(renterPriv api.PrivateKey, objectKey, outputPath string) ([]by
 		hostContracts[c.HostKey()] = c
 	}
 
+	return &downloadContracts{
+		renterPriv:    renterPriv,
+		hostContracts: hostContracts,
+		resolved:      make(map[api.PublicKey]api.Contract),
+	}, nil
+}
+
+// contract returns the contract to use for the host. ok is false if there is
+// no usable contract with the host.
+func (dc *downloadContracts) contract(hostKey api.PublicKey) (_ api.Contract, ok bool, _ error) {
+	if _, ok := dc.hostContracts[hostKey]; !ok {
+		return api.Contract{}, false, nil
+	} else if contract, ok := dc.resolved[hostKey]; ok {
+		return contract, true, nil
+	}
+
+	// grab the host's net address from the renterd hostdb
+	host, err := renterdClient.Host(hostKey)
+	if err != nil {
+		return api.Contract{}, false, fmt.Errorf("failed to get host: %w", err)
+	}
+	netaddress := host.Announcements[len(host.Announcements)-1].NetAddress
+
+	contract := api.Contract{
+		HostKey:   hostKey,
+		HostIP:    netaddress,
+		ID:        dc.hostContracts[hostKey].ID(),
+		RenterKey: dc.renterPriv,
+	}
+	dc.resolved[hostKey] = contract
+	return contract, true, nil
+}
+
+// forSlices returns the contracts needed to download slices. An error is
+// returned if any slice does not have enough hosts with usable contracts to
+// be recovered.
+func (dc *downloadContracts) forSlices(slices []slab.Slice) ([]api.Contract, error) {
 	// find a contract for each shard
 	added := make(map[api.PublicKey]bool)
 	var contracts []api.Contract
-	var fileLength int64
-	for _, slab := range obj.Slabs {
+	for _, slab := range slices {
 		var count uint8
 		for _, shard := range slab.Shards {
-			// if there is no contract for this host, skip it
-			if _, ok := hostContracts[shard.Host]; !ok {
+			contract, ok, err := dc.contract(shard.Host)
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				// if there is no contract for this host, skip it
 				continue
 			}
 
 			if !added[shard.Host] {
-				// grab the host's net address from the renterd hostdb
-				host, err := renterdClient.Host(shard.Host)
-				if err != nil {
-					return nil, fmt.Errorf("failed to get host: %w", err)
-				}
-				netaddress := host.Announcements[len(host.Announcements)-1].NetAddress
-
-				contracts = append(contracts, api.Contract{
-					HostKey:   shard.Host,
-					HostIP:    netaddress,
-					ID:        hostContracts[shard.Host].ID(),
-					RenterKey: renterPriv,
-				})
+				contracts = append(contracts, contract)
 				added[shard.Host] = true
 			}
 			count++
@@ -358,13 +831,157 @@ func downloadFile(renterPriv api.PrivateKey, objectKey, outputPath string) ([]by
 		if count < slab.MinShards {
 			return nil, errors.New("not enough contracts available to download file")
 		}
+	}
+	return contracts, nil
+}
+
+// forHosts returns a usable contract for every host storing a shard of slabs
+// and the number of hosts without a usable contract.
+func (dc *downloadContracts) forHosts(slabs []slab.Slab) (contracts []api.Contract, missing int, err error) {
+	added := make(map[api.PublicKey]bool)
+	for _, s := range slabs {
+		for _, shard := range s.Shards {
+			if added[shard.Host] {
+				continue
+			}
+			added[shard.Host] = true
+
+			contract, ok, err := dc.contract(shard.Host)
+			if err != nil {
+				return nil, 0, err
+			} else if !ok {
+				missing++
+				continue
+			}
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts, missing, nil
+}
+
+// normalizeKey returns key without the leading slash renterd includes in
+// object entries, so keys from listings and from the user can be compared
+// and passed back to renterd.
+func normalizeKey(key string) string {
+	return strings.TrimLeft(key, "/")
+}
+
+// objectKeys returns the normalized keys of every object under prefix,
+// descending into sub-directories.
+func objectKeys(prefix string) ([]string, error) {
+	return walkObjectKeys(renterdClient.ObjectEntries, prefix)
+}
+
+// walkObjectKeys returns the normalized keys of every object under prefix,
+// using list to get the entries of each directory.
+func walkObjectKeys(list func(string) ([]string, error), prefix string) ([]string, error) {
+	prefix = normalizeKey(prefix)
+	entries, err := list(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object entries for %v: %w", prefix, err)
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		key := normalizeKey(entry)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		// entries ending in a slash are directories
+		if strings.HasSuffix(key, "/") {
+			children, err := walkObjectKeys(list, key)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, children...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// downloadPrefix downloads every object under prefix to outputDir, mirroring
+// the object namespace as directories.
+func downloadPrefix(dc *downloadContracts, prefix, outputDir string) error {
+	prefix = normalizeKey(prefix)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	keys, err := objectKeys(prefix)
+	if err != nil {
+		return err
+	} else if len(keys) == 0 {
+		return fmt.Errorf("no objects found under %q", prefix)
+	}
+
+	log.Printf("Downloading %v objects", len(keys))
+	for i, key := range keys {
+		// refuse keys that would escape the output directory
+		rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(key, prefix)))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("object key %v is not a valid local path", key)
+		}
+		outputPath := filepath.Join(outputDir, rel)
+
+		if !dryRun {
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %v: %w", key, err)
+			} else if !skipConfirm && !resumeDownload && !confirmOverwrite(outputPath) {
+				log.Printf("Skipping %v", key)
+				continue
+			}
+		}
+
+		start := time.Now()
+		checksum, err := downloadFile(dc, key, outputPath, 0, 0, resumeDownload)
+		if err != nil {
+			return fmt.Errorf("failed to download %v: %w", key, err)
+		}
+		if !dryRun {
+			log.Printf("Downloaded %v (%v/%v) in %v (%v %x)", key, i+1, len(keys), time.Since(start), hashAlgo, checksum)
+		}
+	}
+	return nil
+}
+
+// confirmOverwrite asks the user whether an existing file should be
+// overwritten. It returns true if the file does not exist.
+func confirmOverwrite(path string) bool {
+	if _, err := os.Stat(path); err != nil {
+		return true
+	}
+	return promptConfirm(fmt.Sprintf("file %v already exists. Overwrite?", path))
+}
 
-		fileLength += int64(slab.Length)
+// downloadFile downloads length bytes of an object starting at offset to
+// outputPath and returns the checksum of the downloaded data. If resume is
+// true, the complete slabs already in outputPath are kept and only the
+// remaining slabs are downloaded.
+func downloadFile(dc *downloadContracts, objectKey, outputPath string, offset, length int64, resume bool) ([]byte, error) {
+	obj, err := renterdClient.Object(objectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	// only download the slabs covering the requested range
+	slices, err := sliceRange(obj.Slabs, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	contracts, err := dc.forSlices(slices)
+	if err != nil {
+		return nil, err
 	}
 
 	if dryRun {
-		n := len(obj.Slabs)
-		for i, slab := range obj.Slabs {
+		n := len(slices)
+		for i, slab := range slices {
 			js, _ := json.MarshalIndent(api.SlabsDownloadRequest{
 				Slab:      slab,
 				Contracts: contracts,
@@ -375,33 +992,153 @@ func downloadFile(renterPriv api.PrivateKey, objectKey, outputPath string) ([]by
 		return nil, nil
 	}
 
+	h, err := newHasher(hashAlgo)
+	if err != nil {
+		return nil, err
+	}
+
 	// download the file
-	f, err := os.Create(outputPath)
+	var f *os.File
+	if resume {
+		f, err = os.OpenFile(outputPath, os.O_RDWR|os.O_CREATE, 0666)
+	} else {
+		f, err = os.Create(outputPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 	defer f.Close()
 
-	var h hash.Hash
-	switch strings.ToLower(hashAlgo) {
-	case "sha256":
-		h = sha256.New()
-	case "sha1":
-		h = sha1.New()
-	case "md5":
-		h = md5.New()
-	default:
-		return nil, fmt.Errorf("unknown hash algorithm: %v", hashAlgo)
-	}
-	mw := io.MultiWriter(f, h)
+	if resume {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file: %w", err)
+		}
 
-	for i, slab := range obj.Slabs {
-		if err := renterdClient.DownloadSlab(mw, slab, contracts); err != nil {
-			return nil, fmt.Errorf("failed to download slab %v: %w", i, err)
+		// discard any partial slab at the end of the file and hash the
+		// complete slabs so the checksum covers the whole file
+		var complete int
+		complete, offset = resumePoint(slices, fi.Size())
+		if err := f.Truncate(offset); err != nil {
+			return nil, fmt.Errorf("failed to truncate file: %w", err)
+		} else if _, err := io.Copy(h, io.LimitReader(f, offset)); err != nil {
+			return nil, fmt.Errorf("failed to hash existing data: %w", err)
+		} else if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek file: %w", err)
 		}
+		if complete > 0 {
+			log.Printf("Resuming download at byte %v, %v of %v slabs already downloaded", offset, complete, len(slices))
+		}
+		slices = slices[complete:]
 	}
-	if err := f.Sync(); err != nil {
+	mw := io.MultiWriter(f, h)
+
+	if err := downloadSlices(mw, slices, contracts, parallel); err != nil {
+		return nil, err
+	} else if err := f.Sync(); err != nil {
 		return nil, fmt.Errorf("failed to sync file: %w", err)
 	}
 	return h.Sum(nil), nil
 }
+
+// slabReferences returns the keys of every object other than exclude that
+// references each slab, indexed by the slab's encryption key. The listing is
+// treated as incomplete, and an error is returned, if it does not include
+// exclude.
+func slabReferences(exclude string) (map[slab.EncryptionKey][]string, error) {
+	exclude = normalizeKey(exclude)
+	keys, err := objectKeys("")
+	if err != nil {
+		return nil, err
+	}
+
+	var found bool
+	refs := make(map[slab.EncryptionKey][]string)
+	for _, key := range keys {
+		if key == exclude {
+			found = true
+			continue
+		}
+
+		obj, err := renterdClient.Object(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object %v: %w", key, err)
+		}
+		for _, s := range obj.Slabs {
+			refs[s.Key] = append(refs[s.Key], key)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("object listing does not include %v", exclude)
+	}
+	return refs, nil
+}
+
+// deleteObject removes an object from renterd and reports which of its slabs
+// are still referenced by other objects. If prune is true, the sectors of
+// slabs that are no longer referenced are deleted from their hosts. Nothing
+// is deleted if the references can't be checked against every object.
+func deleteObject(renterPriv api.PrivateKey, key string, prune bool) error {
+	key = normalizeKey(key)
+	obj, err := renterdClient.Object(key)
+	if err != nil {
+		return fmt.Errorf("failed to get object: %w", err)
+	}
+
+	// packed uploads share slabs between objects, check every other object
+	// to see which slabs are still in use
+	refs, err := slabReferences(key)
+	if err != nil {
+		return fmt.Errorf("failed to get slab references: %w", err)
+	}
+
+	var unreferenced []slab.Slab
+	seen := make(map[slab.EncryptionKey]bool)
+	tbl := table.New("Slab", "Shards", "Status", "Shared With")
+	for i, s := range obj.Slabs {
+		if seen[s.Key] {
+			continue
+		}
+		seen[s.Key] = true
+
+		if shared := refs[s.Key]; len(shared) > 0 {
+			tbl.AddRow(i+1, len(s.Shards), "shared", strings.Join(shared, ", "))
+			continue
+		}
+		tbl.AddRow(i+1, len(s.Shards), "unreferenced", "")
+		unreferenced = append(unreferenced, s.Slab)
+	}
+	tbl.Print()
+
+	if dryRun {
+		log.Printf("dry run: %v has %v unreferenced slabs", key, len(unreferenced))
+		return nil
+	}
+
+	if err := renterdClient.DeleteObject(key); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	log.Printf("Deleted object %v, %v of %v slabs are no longer referenced", key, len(unreferenced), len(seen))
+
+	if !prune || len(unreferenced) == 0 {
+		return nil
+	}
+
+	// find a usable contract for every host storing an unreferenced sector
+	dc, err := newDownloadContracts(renterPriv)
+	if err != nil {
+		return fmt.Errorf("failed to get contracts: %w", err)
+	}
+	contracts, missing, err := dc.forHosts(unreferenced)
+	if err != nil {
+		return err
+	} else if missing > 0 {
+		log.Printf("%v hosts have no usable contract, their sectors will expire with the contract", missing)
+	}
+
+	if err := renterdClient.DeleteSlabs(unreferenced, contracts); err != nil {
+		return fmt.Errorf("failed to prune slabs: %w", err)
+	}
+	log.Printf("Pruned %v slabs", len(unreferenced))
+	return nil
+}
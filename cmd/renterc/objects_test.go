@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.sia.tech/renterd/slab"
+)
+
+func TestWalkObjectKeys(t *testing.T) {
+	// renterd lists the entries of a directory with a leading slash and
+	// requests for /objects/<path> are looked up as "/<path>"
+	dirs := map[string][]string{
+		"/":             {"/foo", "/photos/", "/docs/"},
+		"/photos/":      {"/photos/a.jpg", "/photos/2022/"},
+		"/photos/2022/": {"/photos/2022/b.jpg", "/photos/2022/c.jpg"},
+		"/docs/":        {"/docs/readme.md", "/docs/readme.md"},
+		"/empty/":       nil,
+	}
+	list := func(path string) ([]string, error) {
+		if strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("path %q has a leading slash", path)
+		}
+		entries, ok := dirs["/"+path]
+		if !ok {
+			return nil, fmt.Errorf("unknown path %q", path)
+		}
+		return entries, nil
+	}
+
+	tests := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"", []string{"foo", "photos/a.jpg", "photos/2022/b.jpg", "photos/2022/c.jpg", "docs/readme.md"}},
+		{"/", []string{"foo", "photos/a.jpg", "photos/2022/b.jpg", "photos/2022/c.jpg", "docs/readme.md"}},
+		{"photos/", []string{"photos/a.jpg", "photos/2022/b.jpg", "photos/2022/c.jpg"}},
+		{"/photos/", []string{"photos/a.jpg", "photos/2022/b.jpg", "photos/2022/c.jpg"}},
+		{"photos/2022/", []string{"photos/2022/b.jpg", "photos/2022/c.jpg"}},
+		{"empty/", nil},
+	}
+	for _, test := range tests {
+		keys, err := walkObjectKeys(list, test.prefix)
+		if err != nil {
+			t.Fatalf("prefix %q: %v", test.prefix, err)
+		} else if !reflect.DeepEqual(keys, test.keys) {
+			t.Fatalf("prefix %q: expected %v, got %v", test.prefix, test.keys, keys)
+		}
+	}
+
+	if _, err := walkObjectKeys(list, "missing/"); err == nil {
+		t.Fatal("expected an error listing a missing directory")
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"foo", "foo"},
+		{"/foo", "foo"},
+		{"//foo", "foo"},
+		{"/dir/", "dir/"},
+		{"/dir/foo", "dir/foo"},
+	}
+	for _, test := range tests {
+		if got := normalizeKey(test.key); got != test.want {
+			t.Fatalf("normalizeKey(%q): expected %q, got %q", test.key, test.want, got)
+		}
+	}
+}
+
+func TestSliceRange(t *testing.T) {
+	slabs := []slab.Slice{
+		{Offset: 0, Length: 10},
+		{Offset: 5, Length: 20},
+		{Offset: 0, Length: 5},
+	}
+
+	type region struct {
+		Offset, Length uint32
+	}
+	tests := []struct {
+		offset, length int64
+		want           []region
+		err            bool
+	}{
+		{0, 0, []region{{0, 10}, {5, 20}, {0, 5}}, false},
+		{0, 35, []region{{0, 10}, {5, 20}, {0, 5}}, false},
+		{5, 0, []region{{5, 5}, {5, 20}, {0, 5}}, false},
+		{10, 0, []region{{5, 20}, {0, 5}}, false},
+		{12, 10, []region{{7, 10}}, false},
+		{8, 5, []region{{8, 2}, {5, 3}}, false},
+		{30, 100, []region{{0, 5}}, false},
+		{35, 0, nil, false},
+		{36, 0, nil, true},
+		{-1, 0, nil, true},
+		{0, -1, nil, true},
+	}
+	for _, test := range tests {
+		slices, err := sliceRange(slabs, test.offset, test.length)
+		if test.err {
+			if err == nil {
+				t.Fatalf("offset %v length %v: expected an error", test.offset, test.length)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("offset %v length %v: %v", test.offset, test.length, err)
+		}
+
+		var got []region
+		for _, s := range slices {
+			got = append(got, region{s.Offset, s.Length})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("offset %v length %v: expected %v, got %v", test.offset, test.length, test.want, got)
+		}
+	}
+}
+
+func TestResumePoint(t *testing.T) {
+	slices := []slab.Slice{
+		{Length: 10},
+		{Length: 20},
+		{Length: 5},
+	}
+
+	tests := []struct {
+		size   int64
+		n      int
+		offset int64
+	}{
+		{0, 0, 0},
+		{9, 0, 0},
+		{10, 1, 10},
+		{29, 1, 10},
+		{30, 2, 30},
+		{34, 2, 30},
+		{35, 3, 35},
+		{40, 3, 35},
+	}
+	for _, test := range tests {
+		n, offset := resumePoint(slices, test.size)
+		if n != test.n || offset != test.offset {
+			t.Fatalf("size %v: expected (%v, %v), got (%v, %v)", test.size, test.n, test.offset, n, offset)
+		}
+	}
+}
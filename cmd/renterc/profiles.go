@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+)
+
+// A redundancyProfile is a named set of erasure coding parameters for uploads.
+type redundancyProfile struct {
+	MinShards   uint8 `json:"minShards"`
+	TotalShards uint8 `json:"totalShards"`
+}
+
+// defaultProfiles are always available and can be overridden by the profiles
+// in the data directory.
+var defaultProfiles = map[string]redundancyProfile{
+	"none":    {MinShards: 1, TotalShards: 1},
+	"mirror":  {MinShards: 1, TotalShards: 3},
+	"archive": {MinShards: 10, TotalShards: 30},
+}
+
+var (
+	profilesCmd = &cobra.Command{
+		Use:   "profiles",
+		Short: "list the redundancy profiles",
+		Long:  "renterc objects profiles",
+		Run: func(cmd *cobra.Command, args []string) {
+			profiles, err := loadProfiles(dataDir)
+			if err != nil {
+				log.Fatalln("failed to load profiles:", err)
+			}
+
+			names := make([]string, 0, len(profiles))
+			for name := range profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			tbl := table.New("Name", "Min Shards", "Total Shards", "Expansion")
+			for _, name := range names {
+				p := profiles[name]
+				tbl.AddRow(name, p.MinShards, p.TotalShards, fmt.Sprintf("%.2fx", p.expansion()))
+			}
+			tbl.Print()
+		},
+	}
+
+	setProfileCmd = &cobra.Command{
+		Use:   "set",
+		Short: "add or replace a redundancy profile",
+		Long:  "renterc objects profiles set <name> <min shards> <total shards>",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return fmt.Errorf("expected 3 arguments <name> <min shards> <total shards>, got %d", len(args))
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			m, err := strconv.ParseUint(args[1], 10, 8)
+			if err != nil {
+				log.Fatalln("failed to parse min shards:", err)
+			}
+			n, err := strconv.ParseUint(args[2], 10, 8)
+			if err != nil {
+				log.Fatalln("failed to parse total shards:", err)
+			}
+
+			p := redundancyProfile{MinShards: uint8(m), TotalShards: uint8(n)}
+			if err := p.validate(); err != nil {
+				log.Fatalln("invalid profile:", err)
+			} else if err := saveProfile(dataDir, args[0], p); err != nil {
+				log.Fatalln("failed to save profile:", err)
+			}
+			log.Printf("Saved profile %v (%v)", args[0], p)
+		},
+	}
+)
+
+// validate checks that the profile's erasure coding parameters are usable.
+func (p redundancyProfile) validate() error {
+	switch {
+	case p.MinShards < 1:
+		return errors.New("min shards must be at least 1")
+	case p.TotalShards < p.MinShards:
+		return fmt.Errorf("total shards (%v) must be at least min shards (%v)", p.TotalShards, p.MinShards)
+	}
+	return nil
+}
+
+// expansion returns the ratio of uploaded data to original data.
+func (p redundancyProfile) expansion() float64 {
+	return float64(p.TotalShards) / float64(p.MinShards)
+}
+
+// String implements fmt.Stringer.
+func (p redundancyProfile) String() string {
+	return fmt.Sprintf("%v-of-%v", p.MinShards, p.TotalShards)
+}
+
+// profilesPath returns the path of the profiles file in the data directory.
+func profilesPath(dataDir string) string {
+	return filepath.Join(dataDir, "profiles.json")
+}
+
+// loadSavedProfiles loads the profiles stored in the data directory.
+func loadSavedProfiles(dataDir string) (map[string]redundancyProfile, error) {
+	profiles := make(map[string]redundancyProfile)
+	buf, err := os.ReadFile(profilesPath(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	} else if err := json.Unmarshal(buf, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to decode profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// loadProfiles returns the default profiles merged with the profiles stored in
+// the data directory.
+func loadProfiles(dataDir string) (map[string]redundancyProfile, error) {
+	saved, err := loadSavedProfiles(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]redundancyProfile, len(defaultProfiles)+len(saved))
+	for name, p := range defaultProfiles {
+		profiles[name] = p
+	}
+	for name, p := range saved {
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// saveProfile adds or replaces a profile in the data directory.
+func saveProfile(dataDir, name string, p redundancyProfile) error {
+	profiles, err := loadSavedProfiles(dataDir)
+	if err != nil {
+		return err
+	}
+	profiles[name] = p
+
+	buf, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	} else if err := os.WriteFile(profilesPath(dataDir), buf, 0600); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/siad/types"
+)
+
+// A contractQuote is the cost breakdown of a contract with a host.
+type contractQuote struct {
+	HostKey     api.PublicKey
+	NetAddress  string
+	Settings    rhp.HostSettings
+	StartHeight uint64
+
+	ContractPrice types.Currency
+	Storage       types.Currency
+	Upload        types.Currency
+	Download      types.Currency
+	// Collateral is the host's collateral, it is not paid by the renter but
+	// increases the fees.
+	Collateral types.Currency
+	// Fees are the siafund fee and any other costs added by the host.
+	Fees types.Currency
+	// MinerFee is the miner fee renterd adds when funding the formation
+	// transaction.
+	MinerFee types.Currency
+	Total    types.Currency
+
+	contract types.FileContract
+	// funding is the amount the formation transaction is funded with,
+	// excluding the miner fee.
+	funding types.Currency
+}
+
+var (
+	quoteCmd = &cobra.Command{
+		Use:   "quote",
+		Short: "get the cost of contract(s) with host(s)",
+		Long: `renterc contracts quote [flags] <host public key 1>[@<address>] [host public key 2 ...]
+
+Scans the hosts and prints the cost of a contract funded for --storage bytes of storage, --upload bytes of upload and --download bytes of download lasting --duration blocks with each host. The miner fee is estimated by funding the formation transaction with renterd and releasing its inputs again. No funds are spent.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("at least one host is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			contractUsage, err := parseUsageFlags()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			contractDuration, err := parseBlockDurStr(contractDurationStr)
+			if err != nil {
+				log.Fatalln("failed to parse contract duration:", err)
+			}
+
+			var quotes []contractQuote
+			var failed int
+			for _, arg := range args {
+				hostKey, hostAddr, err := parseHostArg(arg)
+				if err != nil {
+					log.Fatalln("failed to parse host:", err)
+				}
+				q, err := quoteContract(renterPriv, hostKey, hostAddr, contractUsage, contractDuration)
+				if err != nil {
+					log.Printf("failed to quote contract with host %v: %v", hostKey, err)
+					failed++
+					continue
+				}
+				quotes = append(quotes, q)
+			}
+			if len(quotes) > 0 {
+				printQuotes(quotes)
+			}
+			if failed > 0 {
+				log.Fatalf("failed to quote %v of %v contracts", failed, len(args))
+			}
+		},
+	}
+)
+
+// A usageEstimate is the expected number of bytes stored, uploaded and
+// downloaded over a contract's duration.
+type usageEstimate struct {
+	Storage  uint64
+	Upload   uint64
+	Download uint64
+}
+
+// uniformUsage returns a usage estimate of n bytes of storage, upload and
+// download.
+func uniformUsage(n uint64) usageEstimate {
+	return usageEstimate{Storage: n, Upload: n, Download: n}
+}
+
+// parseUsageFlags parses the --storage, --upload and --download flags.
+func parseUsageFlags() (usage usageEstimate, err error) {
+	if usage.Storage, err = parseByteStr(contractStorageStr); err != nil {
+		return usageEstimate{}, fmt.Errorf("failed to parse storage: %w", err)
+	} else if usage.Upload, err = parseByteStr(contractUploadStr); err != nil {
+		return usageEstimate{}, fmt.Errorf("failed to parse upload: %w", err)
+	} else if usage.Download, err = parseByteStr(contractDownloadStr); err != nil {
+		return usageEstimate{}, fmt.Errorf("failed to parse download: %w", err)
+	}
+	return usage, nil
+}
+
+// estimateContract returns the estimated cost of the usage over duration
+// blocks with the host and the collateral the host should put up. Collateral
+// is only requested for the stored data and is capped at the host's max
+// collateral. The fees and total are not set.
+func estimateContract(settings rhp.HostSettings, usage usageEstimate, duration uint64) contractQuote {
+	collateral := settings.Collateral.Mul64(usage.Storage).Mul64(duration)
+	if !settings.MaxCollateral.IsZero() && collateral.Cmp(settings.MaxCollateral) > 0 {
+		collateral = settings.MaxCollateral
+	}
+	return contractQuote{
+		Settings:      settings,
+		ContractPrice: settings.ContractPrice,
+		Storage:       settings.StoragePrice.Mul64(usage.Storage).Mul64(duration),
+		Upload:        settings.UploadBandwidthPrice.Mul64(usage.Upload),
+		Download:      settings.DownloadBandwidthPrice.Mul64(usage.Download),
+		Collateral:    collateral,
+	}
+}
+
+// renterFunds returns the funds the renter allocates to the contract.
+func (q contractQuote) renterFunds() types.Currency {
+	return q.ContractPrice.Add(q.Storage).Add(q.Upload).Add(q.Download)
+}
+
+// quoteContract scans the host and prepares a contract funded for the usage
+// lasting duration blocks. If hostAddr is empty, the host's address is looked
+// up from the configured host source. The formation transaction is funded to
+// estimate the miner fee and its inputs are released. No funds are spent.
+func quoteContract(renterPriv api.PrivateKey, hostPub api.PublicKey, hostAddr string, usage usageEstimate, duration uint64) (contractQuote, error) {
+	// get the wallet's address
+	renterAddr, err := renterdClient.WalletAddress()
+	if err != nil {
+		return contractQuote{}, fmt.Errorf("failed to get wallet address: %w", err)
+	}
+
+	// get the current block height
+	tip, err := renterdClient.ConsensusTip()
+	if err != nil {
+		return contractQuote{}, fmt.Errorf("failed to get consensus tip: %w", err)
+	}
+
+	if hostAddr == "" {
+		hostAddr, err = hostAddress(hostPub)
+		if err != nil {
+			return contractQuote{}, err
+		}
+	}
+
+	// get the host's current settings
+	settings, err := renterdClient.RHPScan(hostPub, hostAddr)
+	if err != nil {
+		return contractQuote{}, fmt.Errorf("failed to scan host: %w", err)
+	}
+
+	q := estimateContract(settings, usage, duration)
+	q.HostKey = hostPub
+	q.NetAddress = hostAddr
+	q.StartHeight = tip.Height
+
+	// prepare the contract for formation
+	q.contract, q.funding, err = renterdClient.RHPPrepareForm(renterPriv, hostPub, q.renterFunds(), renterAddr, q.Collateral, tip.Height+duration, settings)
+	if err != nil {
+		return contractQuote{}, fmt.Errorf("failed to prepare contract: %w", err)
+	}
+	if q.funding.Cmp(q.renterFunds()) > 0 {
+		q.Fees = q.funding.Sub(q.renterFunds())
+	}
+
+	// renterd sets the miner fee from the size of the unfunded transaction,
+	// so funding the same transaction during formation adds the same fee
+	formTxn := types.Transaction{
+		FileContracts: []types.FileContract{q.contract},
+	}
+	if _, _, err := renterdClient.WalletFund(&formTxn, q.funding); err != nil {
+		return contractQuote{}, fmt.Errorf("failed to estimate miner fee: %w", err)
+	}
+	renterdClient.WalletDiscard(formTxn)
+	if len(formTxn.MinerFees) != 0 {
+		q.MinerFee = formTxn.MinerFees[0]
+	}
+	q.Total = q.funding.Add(q.MinerFee)
+	return q, nil
+}
+
+// sumQuotes returns the total cost of the quotes.
+func sumQuotes(quotes []contractQuote) types.Currency {
+	total := types.ZeroCurrency
+	for _, q := range quotes {
+		total = total.Add(q.Total)
+	}
+	return total
+}
+
+// printQuotes prints the cost breakdown of each quote.
+func printQuotes(quotes []contractQuote) {
+	tbl := table.New("Host", "Contract Price", "Storage", "Upload", "Download", "Collateral", "Fees", "Miner Fee", "Total")
+	for _, q := range quotes {
+		tbl.AddRow(q.HostKey, q.ContractPrice.HumanString(), q.Storage.HumanString(), q.Upload.HumanString(), q.Download.HumanString(), q.Collateral.HumanString(), q.Fees.HumanString(), q.MinerFee.HumanString(), q.Total.HumanString())
+	}
+	tbl.Print()
+	if len(quotes) > 1 {
+		log.Printf("Total: %v", sumQuotes(quotes).HumanString())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/siad/types"
+)
+
+// A contractRecord is renterc's local record of a contract it formed or
+// renewed. renterd only stores the latest revision, so the initial funding
+// and the host's prices are recorded to account for spending.
+type contractRecord struct {
+	HostKey      string         `json:"hostKey"`
+	StartHeight  uint64         `json:"startHeight"`
+	InitialFunds types.Currency `json:"initialFunds"`
+	// InitialFileSize is the size of the data carried forward from a renewed
+	// contract, its storage was not paid for with the contract's funds.
+	InitialFileSize uint64 `json:"initialFileSize"`
+
+	StoragePrice  types.Currency `json:"storagePrice"`
+	UploadPrice   types.Currency `json:"uploadPrice"`
+	DownloadPrice types.Currency `json:"downloadPrice"`
+}
+
+// recordsMu guards the records file against concurrent formations.
+var recordsMu sync.Mutex
+
+// contractRecordsPath returns the path of the contract records file.
+func contractRecordsPath(dataDir string) string {
+	return filepath.Join(dataDir, "contracts.json")
+}
+
+// loadContractRecords loads the contract records from the data directory.
+func loadContractRecords(dataDir string) (map[string]contractRecord, error) {
+	records := make(map[string]contractRecord)
+	buf, err := os.ReadFile(contractRecordsPath(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read contract records: %w", err)
+	} else if err := json.Unmarshal(buf, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode contract records: %w", err)
+	}
+	return records, nil
+}
+
+// recordContract adds a record of a newly formed or renewed contract to the
+// data directory.
+func recordContract(dataDir string, c rhp.Contract, startHeight uint64, settings rhp.HostSettings) error {
+	recordsMu.Lock()
+	defer recordsMu.Unlock()
+
+	records, err := loadContractRecords(dataDir)
+	if err != nil {
+		return err
+	}
+	records[c.ID().String()] = contractRecord{
+		HostKey:         c.HostKey().String(),
+		StartHeight:     startHeight,
+		InitialFunds:    c.RenterFunds(),
+		InitialFileSize: c.Revision.NewFileSize,
+		StoragePrice:    settings.StoragePrice,
+		UploadPrice:     settings.UploadBandwidthPrice,
+		DownloadPrice:   settings.DownloadBandwidthPrice,
+	}
+
+	return saveContractRecords(dataDir, records)
+}
+
+// removeContractRecord removes a contract's record from the data directory.
+func removeContractRecord(dataDir string, id types.FileContractID) error {
+	recordsMu.Lock()
+	defer recordsMu.Unlock()
+
+	records, err := loadContractRecords(dataDir)
+	if err != nil {
+		return err
+	} else if _, ok := records[id.String()]; !ok {
+		return nil
+	}
+	delete(records, id.String())
+	return saveContractRecords(dataDir, records)
+}
+
+// saveContractRecords writes the contract records to the data directory.
+func saveContractRecords(dataDir string, records map[string]contractRecord) error {
+	buf, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode contract records: %w", err)
+	} else if err := os.WriteFile(contractRecordsPath(dataDir), buf, 0600); err != nil {
+		return fmt.Errorf("failed to write contract records: %w", err)
+	}
+	return nil
+}
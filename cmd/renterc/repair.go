@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/object"
+	"go.sia.tech/renterd/slab"
+)
+
+var (
+	// repair command args
+	repairAll bool
+)
+
+var (
+	repairCmd = &cobra.Command{
+		Use:   "repair",
+		Short: "repair objects with shards on unusable hosts",
+		Long: `renterc objects repair [flags] <key>
+renterc objects repair --all
+
+Checks every slab of the object(s) against the currently usable contracts. The lost shards of slabs with fewer healthy shards than their total are reconstructed from the remaining hosts and uploaded to usable hosts that don't store a shard of the slab. Healthy shards are kept. Every object that references a repaired slab, including objects packed with it, is updated in renterd.
+
+Slabs with fewer than m healthy shards cannot be recovered.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if repairAll && len(args) != 0 {
+				return errors.New("object keys are not allowed when using --all")
+			} else if !repairAll && len(args) != 1 {
+				return errors.New("exactly one object key or --all is required")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := repairObjects(renterPriv, args); err != nil {
+				log.Fatalln("failed to repair objects:", err)
+			}
+		},
+	}
+)
+
+// healthyShards returns the number of shards of s stored on hosts with a
+// usable contract.
+func healthyShards(dc *downloadContracts, s slab.Slab) (n int) {
+	for _, shard := range s.Shards {
+		if _, ok := dc.hostContracts[shard.Host]; ok {
+			n++
+		}
+	}
+	return
+}
+
+// repairSlab migrates the shards of s stored on hosts without a usable
+// contract to usable hosts that don't store a shard of s. The healthy shards
+// are kept and s is updated in place.
+func repairSlab(dc *downloadContracts, s *slab.Slab, height uint64, contracts []api.Contract) error {
+	from, _, err := dc.forHosts([]slab.Slab{*s})
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[api.PublicKey]bool)
+	for _, shard := range s.Shards {
+		stored[shard.Host] = true
+	}
+	// renterd uploads the lost shards to the first contracts in to, so the
+	// replacement hosts are listed before the healthy hosts
+	var to []api.Contract
+	for _, c := range contracts {
+		if !stored[c.HostKey] {
+			to = append(to, c)
+		}
+	}
+	if lost := len(s.Shards) - healthyShards(dc, *s); len(to) < lost {
+		return fmt.Errorf("not enough usable contracts with new hosts, need %v, have %v", lost, len(to))
+	}
+	to = append(to, from...)
+
+	if err := renterdClient.MigrateSlab(s, from, to, height); err != nil {
+		return fmt.Errorf("failed to migrate slab: %w", err)
+	}
+	return nil
+}
+
+// repairObjects repairs the slabs of the objects with the given keys, or
+// every object if keys is empty, and updates every object referencing a
+// repaired slab. Objects are tracked by their normalized keys.
+func repairObjects(renterPriv api.PrivateKey, keys []string) error {
+	for i := range keys {
+		keys[i] = normalizeKey(keys[i])
+	}
+
+	// every object is needed since packed objects share slabs
+	allKeys, err := objectKeys("")
+	if err != nil {
+		return err
+	}
+	objects := make(map[string]object.Object, len(allKeys))
+	for _, key := range allKeys {
+		obj, err := renterdClient.Object(key)
+		if err != nil {
+			return fmt.Errorf("failed to get object %v: %w", key, err)
+		}
+		objects[key] = obj
+	}
+	if len(keys) == 0 {
+		keys = allKeys
+	} else if _, ok := objects[keys[0]]; !ok {
+		return fmt.Errorf("object %v not found", keys[0])
+	}
+
+	dc, err := newDownloadContracts(renterPriv)
+	if err != nil {
+		return fmt.Errorf("failed to get contracts: %w", err)
+	}
+	// get every usable contract, the required number depends on the slab
+	contracts, err := getUsableContracts(renterPriv, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get usable contracts: %w", err)
+	}
+	tip, err := renterdClient.ConsensusTip()
+	if err != nil {
+		return fmt.Errorf("failed to get consensus tip: %w", err)
+	}
+
+	repaired := make(map[slab.EncryptionKey]slab.Slab)
+	checked := make(map[slab.EncryptionKey]bool)
+	var failed int
+	tbl := table.New("Object", "Slab", "Healthy", "Total", "Result")
+	for _, key := range keys {
+		for i, s := range objects[key].Slabs {
+			if checked[s.Key] {
+				continue
+			}
+			checked[s.Key] = true
+
+			healthy, total := healthyShards(dc, s.Slab), len(s.Shards)
+			switch {
+			case healthy == total:
+				continue
+			case healthy < int(s.MinShards):
+				tbl.AddRow(key, i+1, healthy, total, "unrecoverable")
+				failed++
+				continue
+			case dryRun:
+				tbl.AddRow(key, i+1, healthy, total, "needs repair")
+				continue
+			}
+
+			log.Printf("Repairing slab %v of %v (%v/%v healthy shards)", i+1, key, healthy, total)
+			migrated := s.Slab
+			if err := repairSlab(dc, &migrated, tip.Height, contracts); err != nil {
+				tbl.AddRow(key, i+1, healthy, total, fmt.Sprintf("failed: %v", err))
+				failed++
+				continue
+			}
+			repaired[s.Key] = migrated
+			tbl.AddRow(key, i+1, healthy, total, "repaired")
+		}
+	}
+	tbl.Print()
+
+	// renterd does not update objects when migrating a slab, store the new
+	// shards in every object that references a repaired slab
+	for key, obj := range objects {
+		var updated bool
+		for i, s := range obj.Slabs {
+			if migrated, ok := repaired[s.Key]; ok {
+				obj.Slabs[i].Slab = migrated
+				updated = true
+			}
+		}
+		if !updated {
+			continue
+		} else if err := renterdClient.AddObject(key, obj); err != nil {
+			return fmt.Errorf("failed to update object %v: %w", key, err)
+		}
+		log.Printf("Updated object %v", key)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v slabs could not be repaired", failed)
+	}
+	log.Printf("Repaired %v slabs", len(repaired))
+	return nil
+}
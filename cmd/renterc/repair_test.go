@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/renterd/slab"
+)
+
+func TestHealthyShards(t *testing.T) {
+	hosts := make([]api.PublicKey, 4)
+	for i := range hosts {
+		hosts[i][0] = byte(i + 1)
+	}
+	// only the first two hosts have usable contracts
+	dc := &downloadContracts{
+		hostContracts: map[api.PublicKey]rhp.Contract{
+			hosts[0]: {},
+			hosts[1]: {},
+		},
+	}
+
+	shards := func(hostIndices ...int) []slab.Sector {
+		sectors := make([]slab.Sector, len(hostIndices))
+		for i, h := range hostIndices {
+			sectors[i].Host = hosts[h]
+		}
+		return sectors
+	}
+
+	tests := []struct {
+		shards  []slab.Sector
+		healthy int
+	}{
+		{shards(0, 1), 2},
+		{shards(0, 1, 2), 2},
+		{shards(0, 2, 3), 1},
+		{shards(0, 0, 1), 3},
+		{shards(2, 3), 0},
+		{nil, 0},
+	}
+	for i, test := range tests {
+		s := slab.Slab{MinShards: 1, Shards: test.shards}
+		if n := healthyShards(dc, s); n != test.healthy {
+			t.Fatalf("test %v: expected %v healthy shards, got %v", i, test.healthy, n)
+		}
+	}
+}
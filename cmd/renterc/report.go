@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/siad/types"
+)
+
+// A contractSpending is the breakdown of a contract's spending.
+type contractSpending struct {
+	InitialFunds types.Currency
+	Storage      types.Currency
+	Upload       types.Currency
+	Download     types.Currency
+	Remaining    types.Currency
+}
+
+var (
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "report the spending of each contract",
+		Long: `renterc contracts report
+
+Prints the initial funding, spending on storage, upload and download, remaining funds, data stored and blocks until the proof window of every contract, followed by the totals across all contracts.
+
+renterd only stores a contract's latest revision, so the initial funding and the host's prices are taken from renterc's records of the contracts it formed or renewed. The spending is split by charging the data added to the contract at the recorded storage and upload prices, the rest is counted as download. Contracts formed without renterc have no record and only their remaining funds are reported.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			tip, err := renterdClient.ConsensusTip()
+			if err != nil {
+				log.Fatalln("failed to get consensus tip:", err)
+			}
+
+			contracts, err := renterdClient.Contracts()
+			if err != nil {
+				log.Fatalln("failed to get contracts:", err)
+			}
+
+			records, err := loadContractRecords(dataDir)
+			if err != nil {
+				log.Fatalln("failed to load contract records:", err)
+			}
+
+			var totals contractSpending
+			var totalSize uint64
+			var unrecorded int
+			tbl := table.New("ID", "Host", "Initial Funds", "Storage", "Upload", "Download", "Remaining", "Data Stored", "Proof Window")
+			for _, c := range contracts {
+				totalSize += c.Revision.NewFileSize
+				window := proofWindowStatus(c, tip.Height)
+
+				record, ok := records[c.ID().String()]
+				if !ok {
+					unrecorded++
+					totals.Remaining = totals.Remaining.Add(c.RenterFunds())
+					tbl.AddRow(c.ID(), c.HostKey(), "-", "-", "-", "-", c.RenterFunds().HumanString(), formatByteStr(c.Revision.NewFileSize), window)
+					continue
+				}
+
+				s := estimateSpending(c, record)
+				totals.InitialFunds = totals.InitialFunds.Add(s.InitialFunds)
+				totals.Storage = totals.Storage.Add(s.Storage)
+				totals.Upload = totals.Upload.Add(s.Upload)
+				totals.Download = totals.Download.Add(s.Download)
+				totals.Remaining = totals.Remaining.Add(s.Remaining)
+				tbl.AddRow(c.ID(), c.HostKey(), s.InitialFunds.HumanString(), s.Storage.HumanString(), s.Upload.HumanString(), s.Download.HumanString(), s.Remaining.HumanString(), formatByteStr(c.Revision.NewFileSize), window)
+			}
+			tbl.AddRow("Total", fmt.Sprintf("%v contracts", len(contracts)), totals.InitialFunds.HumanString(), totals.Storage.HumanString(), totals.Upload.HumanString(), totals.Download.HumanString(), totals.Remaining.HumanString(), formatByteStr(totalSize), "")
+			tbl.Print()
+			if unrecorded > 0 {
+				log.Printf("%v contracts were not formed by renterc, their spending is not included in the totals", unrecorded)
+			}
+		},
+	}
+)
+
+// proofWindowStatus returns the number of blocks until the contract's proof
+// window starts. A contract is expired once its proof window has ended.
+func proofWindowStatus(c rhp.Contract, height uint64) string {
+	switch windowStart := uint64(c.Revision.NewWindowStart); {
+	case height >= uint64(c.Revision.NewWindowEnd):
+		return "expired"
+	case height >= windowStart:
+		return "in progress"
+	default:
+		return fmt.Sprintf("%v blocks", windowStart-height)
+	}
+}
+
+// estimateSpending returns the contract's spending. The data added since the
+// contract was formed or renewed is charged at the recorded storage and
+// upload prices until the contract's end, anything else spent is counted as
+// download.
+func estimateSpending(c rhp.Contract, record contractRecord) (s contractSpending) {
+	s.InitialFunds = record.InitialFunds
+	s.Remaining = c.RenterFunds()
+	if s.InitialFunds.Cmp(s.Remaining) <= 0 {
+		return
+	}
+	spent := s.InitialFunds.Sub(s.Remaining)
+
+	// take the smaller of the estimated cost and the funds left to account
+	take := func(estimate types.Currency) types.Currency {
+		if estimate.Cmp(spent) > 0 {
+			estimate = spent
+		}
+		spent = spent.Sub(estimate)
+		return estimate
+	}
+
+	var added, duration uint64
+	if c.Revision.NewFileSize > record.InitialFileSize {
+		added = c.Revision.NewFileSize - record.InitialFileSize
+	}
+	if c.EndHeight() > record.StartHeight {
+		duration = c.EndHeight() - record.StartHeight
+	}
+	s.Storage = take(record.StoragePrice.Mul64(added).Mul64(duration))
+	s.Upload = take(record.UploadPrice.Mul64(added))
+	s.Download = spent
+	return
+}
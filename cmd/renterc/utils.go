@@ -1,9 +1,14 @@
 package main
 
 import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"math/big"
 	"strings"
+	"sync"
 
 	"go.sia.tech/siad/types"
 )
@@ -42,6 +47,22 @@ func parseByteStr(s string) (uint64, error) {
 	return size, nil
 }
 
+// formatByteStr formats a byte size using the largest binary unit that keeps
+// the value at least 1.
+func formatByteStr(n uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.2f %v", f, units[i])
+}
+
 func parseCurrency(s string) (types.Currency, error) {
 	hastings, err := types.ParseCurrency(s)
 	if err != nil {
@@ -74,3 +95,49 @@ func parseBlockDurStr(s string) (uint64, error) {
 	}
 	return dur, nil
 }
+
+// newHasher returns a new hash.Hash for the named algorithm
+func newHasher(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm: %v", algo)
+	}
+}
+
+// promptConfirm asks the user to confirm an action and returns true if they
+// answer yes.
+func promptConfirm(msg string) bool {
+	fmt.Printf("%v (y/n): ", msg)
+	var confirm string
+	fmt.Scanln(&confirm)
+	s := strings.ToLower(confirm)
+	return s == "y" || s == "yes"
+}
+
+// forEachConcurrent calls fn for each index in [0, n), running at most limit
+// calls at a time.
+func forEachConcurrent(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	"go.sia.tech/renterd/api"
+	"go.sia.tech/renterd/rhp/v2"
+	"go.sia.tech/siad/types"
+)
+
+// sessionTimeout is how long to wait to connect to a host and lock a
+// contract.
+const sessionTimeout = 30 * time.Second
+
+// verify args
+var (
+	fixRevisions bool
+)
+
+// A revisionMismatch is a field of the contract's revision that differs
+// between renterd and the host.
+type revisionMismatch struct {
+	Field   string
+	Renterd string
+	Host    string
+}
+
+var (
+	verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "verify contract revisions with their hosts",
+		Long: `renterc contracts verify [flags] [contract id]
+
+Fetches the latest revision of each contract from its host and compares the revision number, filesize, Merkle root and payouts with the revision stored in renterd. Without a contract id, every active contract is verified.
+
+The renter's and host's signatures on the host's revision are verified before it is compared. Finalized contracts can no longer be revised and fail verification.
+
+With --fix, contracts where the host has a newer revision are updated in renterd. Contracts where renterd has the newer revision can't be fixed from the renter's side and are only reported.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("at most one contract id is allowed")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			tip, err := renterdClient.ConsensusTip()
+			if err != nil {
+				log.Fatalln("failed to get consensus tip:", err)
+			}
+
+			var contracts []rhp.Contract
+			if len(args) == 1 {
+				var id types.FileContractID
+				if err := id.LoadString(args[0]); err != nil {
+					log.Fatalf("failed to parse contract id %v: %v", args[0], err)
+				}
+				c, err := renterdClient.Contract(id)
+				if err != nil {
+					log.Fatalf("failed to get contract %v: %v", id, err)
+				}
+				contracts = append(contracts, c)
+			} else {
+				all, err := renterdClient.Contracts()
+				if err != nil {
+					log.Fatalln("failed to get contracts:", err)
+				}
+				for _, c := range all {
+					// hosts are not required to keep expired contracts
+					if tip.Height <= c.EndHeight() {
+						contracts = append(contracts, c)
+					}
+				}
+			}
+
+			var failed, desynced, fixed int
+			for _, c := range contracts {
+				hostRev, err := fetchHostRevision(renterPriv, c)
+				if errors.Is(err, rhp.ErrContractFinalized) {
+					log.Printf("Contract %v: finalized by host %v, it can no longer be revised", c.ID(), c.HostKey())
+					failed++
+					continue
+				} else if err != nil {
+					log.Printf("Contract %v: failed to get revision from host %v: %v", c.ID(), c.HostKey(), err)
+					failed++
+					continue
+				}
+
+				mismatches := compareRevisions(c.Revision, hostRev.Revision)
+				if len(mismatches) == 0 {
+					log.Printf("Contract %v: in sync at revision %v", c.ID(), c.Revision.NewRevisionNumber)
+					continue
+				}
+				desynced++
+
+				log.Printf("Contract %v: revision does not match host %v", c.ID(), c.HostKey())
+				tbl := table.New("Field", "renterd", "Host")
+				for _, m := range mismatches {
+					tbl.AddRow(m.Field, m.Renterd, m.Host)
+				}
+				tbl.Print()
+
+				if !fixRevisions {
+					continue
+				} else if hostRev.Revision.NewRevisionNumber <= c.Revision.NewRevisionNumber {
+					log.Printf("Contract %v: renterd has the newest revision, it can't be fixed", c.ID())
+					continue
+				} else if err := renterdClient.AddContract(hostRev); err != nil {
+					log.Printf("Contract %v: failed to update revision: %v", c.ID(), err)
+					continue
+				}
+				fixed++
+				log.Printf("Contract %v: updated to the host's revision %v", c.ID(), hostRev.Revision.NewRevisionNumber)
+			}
+
+			log.Printf("Verified %v contracts: %v desynced, %v fixed, %v failed", len(contracts), desynced, fixed, failed)
+			if failed > 0 || desynced > fixed {
+				log.Fatalln("not every contract is in sync with its host")
+			}
+		},
+	}
+)
+
+// fetchHostRevision locks the contract with its host to get the host's latest
+// revision. The session verifies the renter's and host's signatures on the
+// revision and rejects finalized contracts.
+func fetchHostRevision(renterPriv api.PrivateKey, c rhp.Contract) (rhp.Contract, error) {
+	hostKey := c.HostKey()
+	addr, err := hostAddress(hostKey)
+	if err != nil {
+		return rhp.Contract{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionTimeout)
+	defer cancel()
+
+	s, err := rhp.DialSession(ctx, addr, hostKey, c.ID(), renterPriv)
+	if err != nil {
+		return rhp.Contract{}, fmt.Errorf("failed to lock contract: %w", err)
+	}
+	defer s.Close()
+	return s.Contract(), nil
+}
+
+// compareRevisions returns the fields that differ between renterd's and the
+// host's revisions.
+func compareRevisions(renterd, host types.FileContractRevision) (mismatches []revisionMismatch) {
+	check := func(field string, a, b interface{}) {
+		if as, bs := fmt.Sprint(a), fmt.Sprint(b); as != bs {
+			mismatches = append(mismatches, revisionMismatch{Field: field, Renterd: as, Host: bs})
+		}
+	}
+	outputs := func(field string, a, b []types.SiacoinOutput) {
+		if len(a) != len(b) {
+			check(field+" Count", len(a), len(b))
+			return
+		}
+		for i := range a {
+			check(fmt.Sprintf("%v %v", field, i), a[i].Value.String(), b[i].Value.String())
+		}
+	}
+
+	check("Revision Number", renterd.NewRevisionNumber, host.NewRevisionNumber)
+	check("Filesize", renterd.NewFileSize, host.NewFileSize)
+	check("Merkle Root", renterd.NewFileMerkleRoot, host.NewFileMerkleRoot)
+	outputs("Valid Payout", renterd.NewValidProofOutputs, host.NewValidProofOutputs)
+	outputs("Missed Payout", renterd.NewMissedProofOutputs, host.NewMissedProofOutputs)
+	return
+}
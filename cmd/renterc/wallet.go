@@ -6,10 +6,20 @@ import (
 	"log"
 	"strconv"
 
+	"github.com/rodaine/table"
 	"github.com/spf13/cobra"
 	"go.sia.tech/siad/types"
 )
 
+// wallet args
+var (
+	minerFeeStr string
+)
+
+// feeDescription describes how the miner fee of wallet transactions is
+// chosen.
+const feeDescription = `The miner fee is renterd's recommended fee rate times the transaction's size once it is funded and signed. Use --fee-per-byte to set the fee rate or --fee to set the total miner fee, the difference from the fee renterd funded is taken from or returned to the wallet's change. Contract formation and renewal are not affected by these flags.`
+
 var (
 	walletCmd = &cobra.Command{
 		Use:   "wallet",
@@ -55,7 +65,9 @@ var (
 	fragCmd = &cobra.Command{
 		Use:   "frag",
 		Short: "splits the wallet's balance into <n> utxos worth <amt>",
-		Long:  "renterc wallet frag <n> <amt>",
+		Long: `renterc wallet frag <n> <amt>
+
+` + feeDescription,
 		Args: func(cm *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return fmt.Errorf("expected 2 arguments <n> <amt>, got %d", len(args))
@@ -91,8 +103,6 @@ var (
 			}
 
 			fragTxn := types.Transaction{
-				// high miner fees to guarantee acceptance
-				MinerFees:      []types.Currency{types.SiacoinPrecision.Div64(2)},
 				SiacoinOutputs: make([]types.SiacoinOutput, count),
 			}
 
@@ -109,14 +119,11 @@ var (
 				}
 			}
 
-			fundAmount := amount.Mul64(uint64(count))
-			toSign, _, err := renterdClient.WalletFund(&fragTxn, fundAmount)
+			fee, err := fundAndSignTransaction(&fragTxn, amount.Mul64(uint64(count)))
 			if err != nil {
-				log.Fatalln("failed to fund transaction:", err)
-			} else if err := renterdClient.WalletSign(&fragTxn, toSign, types.FullCoveredFields); err != nil {
-				renterdClient.WalletDiscard(fragTxn)
-				log.Fatalln("failed to sign transaction:", err)
+				log.Fatalln(err)
 			}
+			log.Printf("Miner fee: %v", fee.HumanString())
 
 			if dryRun {
 				renterdClient.WalletDiscard(fragTxn)
@@ -133,4 +140,75 @@ var (
 			log.Printf("Successfully broadcast transaction %v", fragTxn.ID())
 		},
 	}
+
+	sendCmd = &cobra.Command{
+		Use:   "send",
+		Short: "send siacoins to address(es)",
+		Long: `renterc wallet send [flags] <address> <amount> [<address 2> <amount 2> ...]
+
+Sends siacoins to one or more addresses in a single transaction. Only the amounts sent are funded, the miner fee is added by renterd. The recipients, miner fee and total are printed and must be confirmed unless -y is given.
+
+` + feeDescription,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 || len(args)%2 != 0 {
+				return fmt.Errorf("expected pairs of <address> <amount>, got %d arguments", len(args))
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			var outputs []types.SiacoinOutput
+			total := types.ZeroCurrency
+			for i := 0; i < len(args); i += 2 {
+				var addr types.UnlockHash
+				if err := addr.LoadString(args[i]); err != nil {
+					log.Fatalf("failed to parse address %v: %v", args[i], err)
+				}
+				amount, err := parseCurrency(args[i+1])
+				if err != nil {
+					log.Fatalf("failed to parse amount %v: %v", args[i+1], err)
+				} else if amount.IsZero() {
+					log.Fatalf("amount sent to %v must be greater than zero", addr)
+				}
+				outputs = append(outputs, types.SiacoinOutput{Value: amount, UnlockHash: addr})
+				total = total.Add(amount)
+			}
+
+			tbl := table.New("Address", "Amount")
+			for _, o := range outputs {
+				tbl.AddRow(o.UnlockHash, o.Value.HumanString())
+			}
+			tbl.Print()
+
+			// fund the transaction first so the confirmation includes the
+			// miner fee set by renterd
+			sendTxn := types.Transaction{
+				SiacoinOutputs: outputs,
+			}
+			fee, err := fundAndSignTransaction(&sendTxn, total)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			log.Printf("Miner fee: %v", fee.HumanString())
+			log.Printf("Total: %v", total.Add(fee).HumanString())
+
+			if !dryRun && !skipConfirm && !promptConfirm(fmt.Sprintf("Send %v to %v addresses with a %v miner fee?", total.HumanString(), len(outputs), fee.HumanString())) {
+				renterdClient.WalletDiscard(sendTxn)
+				log.Fatalln("aborted")
+			}
+
+			if dryRun {
+				renterdClient.WalletDiscard(sendTxn)
+				buf, _ := json.MarshalIndent(sendTxn, "", "  ")
+				log.Println(string(buf))
+				return
+			}
+
+			if err := renterdClient.BroadcastTransaction([]types.Transaction{sendTxn}); err != nil {
+				renterdClient.WalletDiscard(sendTxn)
+				log.Fatalln("failed to broadcast transaction:", err)
+			}
+
+			log.Printf("Successfully broadcast transaction %v", sendTxn.ID())
+		},
+	}
 )